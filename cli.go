@@ -5,14 +5,18 @@ import (
 	"os"
 
 	"github.com/projecteru2/cli/cmd/core"
+	"github.com/projecteru2/cli/cmd/history"
 	"github.com/projecteru2/cli/cmd/image"
 	"github.com/projecteru2/cli/cmd/lambda"
 	"github.com/projecteru2/cli/cmd/network"
 	"github.com/projecteru2/cli/cmd/node"
 	"github.com/projecteru2/cli/cmd/pod"
+	"github.com/projecteru2/cli/cmd/session"
+	"github.com/projecteru2/cli/cmd/spec"
 	"github.com/projecteru2/cli/cmd/status"
 	"github.com/projecteru2/cli/cmd/workload"
 	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/interactive"
 	"github.com/projecteru2/cli/version"
 
 	"github.com/sirupsen/logrus"
@@ -49,11 +53,14 @@ func main() {
 		DisableSliceFlagSeparator: true,
 		Commands: []*cli.Command{
 			core.Command(),
+			history.Command(),
 			image.Command(),
 			lambda.Command(),
 			network.Command(),
 			node.Command(),
 			pod.Command(),
+			session.Command(),
+			spec.Command(),
 			status.Command(),
 			workload.Command(),
 		},
@@ -94,6 +101,13 @@ func main() {
 				EnvVars:     []string{"ERU_OUTPUT_FORMAT"},
 				Destination: &describe.Format,
 			},
+			&cli.StringFlag{
+				Name:        "detach-keys",
+				Usage:       "key sequence to detach from interactive sessions, like `~.` or `ctrl-p,ctrl-q`, empty to disable",
+				Value:       "~.",
+				EnvVars:     []string{"ERU_DETACH_KEYS"},
+				Destination: &interactive.DetachKeys,
+			},
 		},
 	}
 
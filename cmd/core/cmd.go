@@ -19,7 +19,7 @@ func Command() *cli.Command {
 			},
 			{
 				Name:   "watch",
-				Usage:  "",
+				Usage:  "watch addresses of eru-core services, reconnect if broken",
 				Action: utils.ExitCoder(cmdWatchServiceStatus),
 			},
 		},
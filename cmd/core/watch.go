@@ -1,33 +1,53 @@
 package core
 
 import (
+	"context"
 	"fmt"
-	"io"
+	"time"
 
 	"github.com/projecteru2/cli/cmd/utils"
 	corepb "github.com/projecteru2/core/rpc/gen"
 	"github.com/urfave/cli/v2"
 )
 
+type watchServiceStatusOptions struct {
+	client corepb.CoreRPCClient
+}
+
+func (o *watchServiceStatusOptions) run(ctx context.Context) error {
+	w := &utils.Watcher{Name: "WatchServiceStatus"}
+	w.Connect = func(ctx context.Context) (func() error, error) {
+		resp, err := o.client.WatchServiceStatus(ctx, &corepb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println("watch start")
+		return func() error {
+			msg, err := resp.Recv()
+			if err != nil {
+				return err
+			}
+			// eru-core pushes every interval, missing 2 of them means the connection is dead
+			if msg.IntervalInSecond > 0 {
+				w.IdleTimeout = 2 * time.Duration(msg.IntervalInSecond) * time.Second
+			}
+			for id, addr := range msg.Addresses {
+				fmt.Printf("%v: %v\n", id, addr)
+			}
+			return nil
+		}, nil
+	}
+	return w.Run(ctx)
+}
+
 func cmdWatchServiceStatus(c *cli.Context) error {
 	client, err := utils.NewCoreRPCClient(c)
 	if err != nil {
 		return err
 	}
 
-	resp, err := client.WatchServiceStatus(c.Context, &corepb.Empty{})
-	if err != nil {
-		return err
-	}
-	fmt.Println("watch start")
-	for {
-		msg, err := resp.Recv()
-		if err == io.EOF {
-			break
-		}
-		for id, addr := range msg.Addresses {
-			fmt.Printf("%v: %v\n", id, addr)
-		}
+	o := &watchServiceStatusOptions{
+		client: client,
 	}
-	return nil
+	return o.run(c.Context)
 }
@@ -0,0 +1,23 @@
+package history
+
+import (
+	"github.com/projecteru2/cli/cmd/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Command exports history subommands
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:      "history",
+		Usage:     "show deploy revisions of an app by labels of its workloads",
+		ArgsUsage: "<appname>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "entry",
+				Usage: "entry filter or not",
+			},
+		},
+		Action: utils.ExitCoder(cmdHistory),
+	}
+}
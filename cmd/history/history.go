@@ -0,0 +1,120 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/juju/errors"
+	"github.com/urfave/cli/v2"
+)
+
+type historyOptions struct {
+	client corepb.CoreRPCClient
+	name   string
+	entry  string
+}
+
+func (o *historyOptions) run(ctx context.Context) error {
+	resp, err := o.client.ListWorkloads(ctx, &corepb.ListWorkloadsOptions{
+		Appname:    o.name,
+		Entrypoint: o.entry,
+	})
+	if err != nil {
+		return fmt.Errorf("[History] list workloads failed %v", err)
+	}
+
+	revisions := map[string]*types.Revision{}
+	for {
+		w, err := resp.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// workloads deployed by older cli have no revision labels,
+		// they're grouped by image
+		rev := w.Labels[types.LabelRevision]
+		key := rev
+		if key == "" {
+			key = "image:" + w.Image
+		}
+		r, ok := revisions[key]
+		if !ok {
+			r = &types.Revision{
+				Revision:   rev,
+				Image:      w.Image,
+				SpecHash:   w.Labels[types.LabelSpecHash],
+				Deployer:   w.Labels[types.LabelDeployer],
+				DeployedAt: w.Labels[types.LabelDeployedAt],
+				CLIVersion: w.Labels[types.LabelCLIVersion],
+			}
+			revisions[key] = r
+		}
+		r.Count++
+
+		_, entry, _, err := coreutils.ParseWorkloadName(w.Name)
+		if err != nil {
+			continue
+		}
+		found := false
+		for _, e := range r.Entrypoints {
+			found = found || e == entry
+		}
+		if !found {
+			r.Entrypoints = append(r.Entrypoints, entry)
+		}
+	}
+
+	result := []*types.Revision{}
+	for _, r := range revisions {
+		sort.Strings(r.Entrypoints)
+		result = append(result, r)
+	}
+	sortRevisions(result)
+	describe.Revisions(result...)
+	return nil
+}
+
+// sortRevisions sorts revisions latest first, times are parsed since they may be in different zones,
+// revisions without valid time are the last
+func sortRevisions(revisions []*types.Revision) {
+	deployedAt := map[*types.Revision]time.Time{}
+	for _, r := range revisions {
+		if t, err := time.Parse(time.RFC3339, r.DeployedAt); err == nil {
+			deployedAt[r] = t
+		}
+	}
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return deployedAt[revisions[i]].After(deployedAt[revisions[j]])
+	})
+}
+
+func cmdHistory(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("[History] appname must be given")
+	}
+
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return err
+	}
+
+	o := &historyOptions{
+		client: client,
+		name:   name,
+		entry:  c.String("entry"),
+	}
+	return o.run(c.Context)
+}
@@ -0,0 +1,26 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+)
+
+func TestSortRevisions(t *testing.T) {
+	revisions := []*types.Revision{
+		{Revision: "old", DeployedAt: ""},
+		{Revision: "utc", DeployedAt: "2021-06-17T10:00:00Z"},
+		// 09:30 in UTC, earlier than utc though larger as a string
+		{Revision: "shanghai", DeployedAt: "2021-06-17T17:30:00+08:00"},
+		// 11:00 in UTC
+		{Revision: "newyork", DeployedAt: "2021-06-17T07:00:00-04:00"},
+	}
+	sortRevisions(revisions)
+
+	expected := []string{"newyork", "utc", "shanghai", "old"}
+	for i, r := range revisions {
+		if r.Revision != expected[i] {
+			t.Fatalf("revision %d is %s, want %v", i, r.Revision, expected)
+		}
+	}
+}
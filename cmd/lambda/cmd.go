@@ -85,7 +85,7 @@ func Command() *cli.Command {
 			},
 			&cli.BoolFlag{
 				Name:    "stdin",
-				Usage:   "open stdin for workload",
+				Usage:   "open stdin for workload, piped stdin is not binary safe and is refused if it starts with byte 0x80 or 0x1d",
 				Aliases: []string{"s"},
 				Value:   false,
 			},
@@ -126,6 +126,24 @@ func Command() *cli.Command {
 				Name:  "workload-id",
 				Usage: "whether print workload id as prefix or not",
 			},
+			&cli.StringSliceFlag{
+				Name:  "values",
+				Usage: "values file to render commands, env and image as go template, can use multiple times",
+			},
+			&cli.StringSliceFlag{
+				Name:  "set",
+				Usage: "set template value, can use multiple times, overrides values files, e.g., image.tag=v1",
+			},
+			&cli.StringFlag{
+				Name:    "record-session",
+				Usage:   "record the session with --stdin in asciicast v2 format to this file, or to a new file in this directory",
+				EnvVars: []string{"ERU_RECORD_SESSION"},
+			},
+			&cli.BoolFlag{
+				Name:    "require-session-record",
+				Usage:   "refuse sessions with --stdin without --record-session",
+				EnvVars: []string{"ERU_REQUIRE_SESSION_RECORD"},
+			},
 		},
 		Action: utils.ExitCoder(cmdLambdaRun),
 	}
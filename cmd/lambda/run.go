@@ -21,10 +21,12 @@ type runLambdaOptions struct {
 	stdin           bool
 	count           int
 	printWorkloadID bool
+	recorder        *interactive.Recorder
 }
 
 func (o *runLambdaOptions) run(_ context.Context) error {
-	code, err := lambda(o.client, o.opts, o.stdin, o.count, o.printWorkloadID)
+	defer utils.SaveSessionRecording(o.recorder)
+	code, err := lambda(o.client, o.opts, o.stdin, o.count, o.printWorkloadID, o.recorder)
 	if err == nil {
 		return cli.Exit("", code)
 	}
@@ -42,19 +44,25 @@ func cmdLambdaRun(c *cli.Context) error {
 		return err
 	}
 
+	recorder, err := utils.NewSessionRecorder(c, c.Bool("stdin"), strings.Join(opts.DeployOptions.Entrypoint.Commands, " "))
+	if err != nil {
+		return err
+	}
+
 	o := &runLambdaOptions{
 		client:          client,
 		opts:            opts,
 		stdin:           c.Bool("stdin"),
 		count:           c.Int("count"),
 		printWorkloadID: c.Bool("workload-id"),
+		recorder:        recorder,
 	}
 	return o.run(c.Context)
 }
 
 var clrf = []byte{0xa}
 
-func lambda(client corepb.CoreRPCClient, opts *corepb.RunAndWaitOptions, stdin bool, count int, printWorkloadID bool) (code int, err error) {
+func lambda(client corepb.CoreRPCClient, opts *corepb.RunAndWaitOptions, stdin bool, count int, printWorkloadID bool, recorder *interactive.Recorder) (code int, err error) {
 	resp, err := client.RunAndWait(context.Background())
 	if err != nil {
 		return -1, err
@@ -69,6 +77,7 @@ func lambda(client corepb.CoreRPCClient, opts *corepb.RunAndWaitOptions, stdin b
 		Send: func(cmd []byte) error {
 			return resp.Send(&corepb.RunAndWaitOptions{Cmd: cmd})
 		},
+		Recorder: recorder,
 	}
 
 	go func() {
@@ -96,6 +105,11 @@ func generateLambdaOptions(c *cli.Context) (*corepb.RunAndWaitOptions, error) {
 
 	content, modes, owners := utils.GenerateFileOptions(c)
 
+	commands, env, image, err := renderLambdaArgs(c)
+	if err != nil {
+		return nil, fmt.Errorf("[Lambda] render failed %v", err)
+	}
+
 	cpumem := resourcetypes.RawParams{
 		"cpu-request":    c.Float64("cpu-request"),
 		"cpu-limit":      c.Float64("cpu"),
@@ -128,7 +142,7 @@ func generateLambdaOptions(c *cli.Context) (*corepb.RunAndWaitOptions, error) {
 			Name: "lambda",
 			Entrypoint: &corepb.EntrypointOptions{
 				Name:       c.String("name"),
-				Commands:   c.Args().Slice(),
+				Commands:   commands,
 				Privileged: c.Bool("privileged"),
 				Dir:        c.String("working-dir"),
 			},
@@ -137,9 +151,9 @@ func generateLambdaOptions(c *cli.Context) (*corepb.RunAndWaitOptions, error) {
 			NodeFilter: &corepb.NodeFilter{
 				Includes: c.StringSlice("node"),
 			},
-			Image:          c.String("image"),
+			Image:          image,
 			Count:          int32(c.Int("count")),
-			Env:            c.StringSlice("env"),
+			Env:            env,
 			Networks:       utils.GetNetworks(network),
 			OpenStdin:      c.Bool("stdin"),
 			DeployStrategy: corepb.DeployOptions_Strategy(corepb.DeployOptions_Strategy_value[strings.ToUpper(c.String("deploy-strategy"))]),
@@ -150,3 +164,30 @@ func generateLambdaOptions(c *cli.Context) (*corepb.RunAndWaitOptions, error) {
 		},
 	}, nil
 }
+
+// lambda has no specs, so commands, env and image are rendered
+// as go template instead when --values or --set is given
+func renderLambdaArgs(c *cli.Context) ([]string, []string, string, error) {
+	commands, env, image := c.Args().Slice(), c.StringSlice("env"), c.String("image")
+	if !c.IsSet("values") && !c.IsSet("set") {
+		return commands, env, image, nil
+	}
+
+	values, err := utils.GetSpecValues(c.StringSlice("values"), c.StringSlice("set"))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	render := func(s string) (string, error) {
+		b, err := utils.RenderSpec("lambda", []byte(s), values)
+		return string(b), err
+	}
+	for _, ss := range [][]string{commands, env} {
+		for i := range ss {
+			if ss[i], err = render(ss[i]); err != nil {
+				return nil, nil, "", err
+			}
+		}
+	}
+	image, err = render(image)
+	return commands, env, image, err
+}
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"time"
+
 	"github.com/projecteru2/cli/cmd/utils"
 
 	"github.com/urfave/cli/v2"
@@ -84,8 +86,104 @@ func Command() *cli.Command {
 				Action:    utils.ExitCoder(cmdNodeSetStatus),
 			},
 			{
-				Name:   "watch-status",
-				Usage:  "watch status of node, used for heartbeat",
+				Name:      "drain",
+				Usage:     "bypass node and migrate all workloads on it to other nodes, workloads must be deployed by this version of cli",
+				ArgsUsage: nodeArgsUsage,
+				Action:    utils.ExitCoder(cmdNodeDrain),
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "concurrency",
+						Usage: "how many workloads to migrate at the same time",
+						Value: 1,
+					},
+					&cli.DurationFlag{
+						Name:  "wait-timeout",
+						Usage: "how long to wait for a new workload to be healthy, before removing the old one",
+						Value: 5 * time.Minute,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "only show the plan, if other nodes can hold the workloads and which ones can't be migrated",
+					},
+				},
+			},
+			{
+				Name:  "maintenance",
+				Usage: "guided node maintenance, progress is kept locally so it can be resumed",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "start",
+						Usage:     "cordon, check critical workloads, drain, wait till empty and check resource of node",
+						ArgsUsage: nodeArgsUsage,
+						Action:    utils.ExitCoder(cmdNodeMaintenanceStart),
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "critical",
+								Usage: "workloads with any of these labels are critical, node with them won't be drained, e.g. --critical critical=true",
+								Value: cli.NewStringSlice("critical=true"),
+							},
+							&cli.IntFlag{
+								Name:  "concurrency",
+								Usage: "how many workloads to migrate at the same time",
+								Value: 1,
+							},
+							&cli.DurationFlag{
+								Name:  "wait-timeout",
+								Usage: "how long to wait for a new workload to be healthy, before removing the old one",
+								Value: 5 * time.Minute,
+							},
+							&cli.DurationFlag{
+								Name:  "empty-timeout",
+								Usage: "how long to wait for node to be empty after draining",
+								Value: 10 * time.Minute,
+							},
+							&cli.BoolFlag{
+								Name:  "fix",
+								Usage: "fix node resource diffs instead of failing",
+							},
+						},
+					},
+					{
+						Name:      "status",
+						Usage:     "show progress of node maintenance",
+						ArgsUsage: nodeArgsUsage,
+						Action:    utils.ExitCoder(cmdNodeMaintenanceStatus),
+					},
+					{
+						Name:      "finish",
+						Usage:     "set node up, verify its heartbeat and uncordon it",
+						ArgsUsage: nodeArgsUsage,
+						Action:    utils.ExitCoder(cmdNodeMaintenanceFinish),
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "ttl",
+								Usage: "ttl in seconds of the status set by up, heartbeat is verified after it expires",
+								Value: 30,
+							},
+							&cli.DurationFlag{
+								Name:  "heartbeat-timeout",
+								Usage: "how long to wait for node to be alive after the status set by up expires",
+								Value: time.Minute,
+							},
+						},
+					},
+					{
+						Name:      "abort",
+						Usage:     "give up node maintenance at any step, uncordon node and remove the progress",
+						ArgsUsage: nodeArgsUsage,
+						Action:    utils.ExitCoder(cmdNodeMaintenanceAbort),
+					},
+				},
+			},
+			{
+				Name:  "watch-status",
+				Usage: "watch status of node, used for heartbeat",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "idle-timeout",
+						Usage: "reconnect if nothing is received for this long, 0 to disable",
+					},
+				},
 				Action: utils.ExitCoder(cmdNodeWatchStatus),
 			},
 			{
@@ -0,0 +1,306 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// partialFailureExitCode is the exit code when some of the workloads failed, the same as workload commands
+const partialFailureExitCode = 2
+
+type drainNodeOptions struct {
+	client      corepb.CoreRPCClient
+	name        string
+	concurrency int
+	waitTimeout time.Duration
+	dryRun      bool
+}
+
+// drainItem is a workload to migrate, with options to deploy its replacement
+type drainItem struct {
+	workload *corepb.Workload
+	opts     *corepb.DeployOptions
+	group    *types.DrainGroup
+}
+
+func (o *drainNodeOptions) run(ctx context.Context) error {
+	resp, err := o.client.ListNodeWorkloads(ctx, &corepb.GetNodeOptions{Nodename: o.name})
+	if err != nil {
+		return err
+	}
+
+	groups, items := o.plan(ctx, resp.Workloads)
+	describe.DrainPlan(groups...)
+
+	unfit := 0
+	for _, g := range groups {
+		if !g.Fits {
+			unfit++
+		}
+	}
+	if o.dryRun {
+		if unfit > 0 {
+			return cli.Exit(fmt.Sprintf("[Drain] %d of %d entrypoint(s) can't be migrated", unfit, len(groups)), partialFailureExitCode)
+		}
+		return nil
+	}
+	if unfit > 0 {
+		return fmt.Errorf("[Drain] %d of %d entrypoint(s) can't be migrated, node %s is left as it is", unfit, len(groups), o.name)
+	}
+
+	if _, err := o.client.SetNode(ctx, &corepb.SetNodeOptions{
+		Nodename: o.name,
+		Bypass:   corepb.TriOpt_TRUE,
+	}); err != nil {
+		return fmt.Errorf("[Drain] failed to bypass node %s: %v", o.name, err)
+	}
+	logrus.Infof("[Drain] Node %s bypassed, no more workloads will be deployed on it", o.name)
+	if len(items) == 0 {
+		logrus.Infof("[Drain] No workloads on node %s", o.name)
+		return nil
+	}
+
+	results := o.migrateAll(ctx, items)
+	describe.DrainResults(results...)
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("[Drain] %d of %d workload(s) failed, they're left on node %s", failed, len(results), o.name), partialFailureExitCode)
+	}
+	logrus.Infof("[Drain] Node %s drained", o.name)
+	return nil
+}
+
+// plan groups workloads by app and entrypoint, and checks if other nodes can hold each group,
+// groups are checked separately, the latest created workload of a group is used to calculate capacity.
+// CalculateCapacity can't take resources planned for other groups into account,
+// so all groups fitting doesn't mean they fit together, migrating may still fail for lack of resources.
+func (o *drainNodeOptions) plan(ctx context.Context, workloads []*corepb.Workload) ([]*types.DrainGroup, []*drainItem) {
+	sort.Slice(workloads, func(i, j int) bool { return workloads[i].CreateTime > workloads[j].CreateTime })
+
+	groups := []*types.DrainGroup{}
+	byName := map[string]*types.DrainGroup{}
+	items := []*drainItem{}
+	for _, w := range workloads {
+		appname, entry, _, err := coreutils.ParseWorkloadName(w.Name)
+		if err != nil {
+			appname, entry = w.Name, ""
+		}
+		key := appname + "/" + entry
+		g, ok := byName[key]
+		if !ok {
+			g = &types.DrainGroup{Appname: appname, Entrypoint: entry}
+			byName[key] = g
+			groups = append(groups, g)
+		}
+		g.Count++
+		if err != nil {
+			g.Error = fmt.Sprintf("invalid workload name %s", w.Name)
+			continue
+		}
+
+		opts, err := utils.RedeployOptions(w)
+		if err != nil {
+			if g.Error == "" {
+				g.Error = err.Error()
+			}
+			continue
+		}
+		opts.NodeFilter.Excludes = []string{o.name}
+		items = append(items, &drainItem{workload: w, opts: opts, group: g})
+	}
+
+	// the first item of a group is the latest created one
+	checked := map[*types.DrainGroup]bool{}
+	for _, item := range items {
+		g := item.group
+		if checked[g] || g.Error != "" {
+			continue
+		}
+		checked[g] = true
+
+		// options of the item are kept for deploying, use new ones to calculate
+		opts, _ := utils.RedeployOptions(item.workload)
+		opts.NodeFilter.Excludes = []string{o.name}
+		opts.Count = int32(g.Count)
+		opts.DeployStrategy = corepb.DeployOptions_DUMMY
+		resp, err := o.client.CalculateCapacity(ctx, opts)
+		if err != nil {
+			g.Error = fmt.Sprintf("calculate capacity failed %v", err)
+			continue
+		}
+		g.Capacity = resp.Total
+		g.Fits = resp.Total >= int64(g.Count)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Appname != groups[j].Appname {
+			return groups[i].Appname < groups[j].Appname
+		}
+		return groups[i].Entrypoint < groups[j].Entrypoint
+	})
+	return groups, items
+}
+
+// migrateAll migrates workloads at most o.concurrency at the same time
+func (o *drainNodeOptions) migrateAll(ctx context.Context, items []*drainItem) []*types.DrainResult {
+	concurrency := o.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([]*types.DrainResult, len(items))
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, item *drainItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = o.migrate(ctx, item)
+		}(i, item)
+	}
+	wg.Wait()
+	return results
+}
+
+// migrate deploys a replacement of the workload on other nodes, and removes the workload after the new one is healthy,
+// the new one is removed instead if it's not healthy in time. Files shipped to the workload are copied from it
+func (o *drainNodeOptions) migrate(ctx context.Context, item *drainItem) *types.DrainResult {
+	w := item.workload
+	r := &types.DrainResult{ID: w.Id, Name: w.Name}
+
+	data, modes, owners, err := utils.RedeployFiles(ctx, o.client, w)
+	if err != nil {
+		r.Error = fmt.Sprintf("fetch files failed %v", err)
+		logrus.Errorf("[Drain] Failed to fetch files shipped to %s: %v", w.Name, err)
+		return r
+	}
+	if len(data) > 0 {
+		item.opts.Data, item.opts.Modes, item.opts.Owners = data, modes, owners
+	}
+
+	created, err := o.create(ctx, item.opts)
+	if err != nil {
+		r.Error = fmt.Sprintf("create failed %v", err)
+		logrus.Errorf("[Drain] Failed to create replacement of %s: %v", w.Name, err)
+		return r
+	}
+	r.NewID, r.NewName, r.NewNodename = created.Id, created.Name, created.Nodename
+	logrus.Infof("[Drain] Replacement of %s created, %s on %s", w.Name, created.Name, created.Nodename)
+
+	healths, err := utils.WaitHealthy(ctx, o.client, []string{created.Id}, o.waitTimeout)
+	if err != nil || !healths[0].OK() {
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = healths[0].Reason()
+		}
+		r.Error = fmt.Sprintf("replacement not healthy: %s", reason)
+		logrus.Errorf("[Drain] Replacement %s of %s is not healthy: %s, remove it", created.Name, w.Name, reason)
+		if err := o.remove(ctx, created.Id, true); err != nil {
+			r.Error += fmt.Sprintf(", and remove it failed %v", err)
+		}
+		return r
+	}
+
+	if err := o.remove(ctx, w.Id, false); err != nil {
+		r.Error = fmt.Sprintf("replacement is healthy, but remove failed %v", err)
+		logrus.Errorf("[Drain] Failed to remove %s: %v", w.Name, err)
+		return r
+	}
+	logrus.Infof("[Drain] %s migrated to %s", w.Name, created.Nodename)
+	r.Success = true
+	return r
+}
+
+func (o *drainNodeOptions) create(ctx context.Context, opts *corepb.DeployOptions) (*corepb.CreateWorkloadMessage, error) {
+	resp, err := o.client.CreateWorkload(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	var created *corepb.CreateWorkloadMessage
+	for {
+		msg, err := resp.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !msg.Success {
+			return nil, errors.New(msg.Error)
+		}
+		created = msg
+	}
+	if created == nil {
+		return nil, errors.New("no workload created")
+	}
+	return created, nil
+}
+
+func (o *drainNodeOptions) remove(ctx context.Context, id string, force bool) error {
+	resp, err := o.client.RemoveWorkload(ctx, &corepb.RemoveWorkloadOptions{
+		IDs:   []string{id},
+		Force: force,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := resp.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Hook != "" {
+			logrus.Infof("[Drain] Hook output of %s \n%s", coreutils.ShortID(msg.Id), msg.Hook)
+		}
+		if !msg.Success {
+			return fmt.Errorf("remove %s failed", coreutils.ShortID(msg.Id))
+		}
+	}
+}
+
+func cmdNodeDrain(c *cli.Context) error {
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Node name must be given")
+	}
+
+	o := &drainNodeOptions{
+		client:      client,
+		name:        name,
+		concurrency: c.Int("concurrency"),
+		waitTimeout: c.Duration("wait-timeout"),
+		dryRun:      c.Bool("dry-run"),
+	}
+	return o.run(c.Context)
+}
@@ -0,0 +1,417 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	maintenanceDir = "maintenance"
+	// maintenancePollInterval is how often to check while waiting for the node to be empty or alive
+	maintenancePollInterval = 5 * time.Second
+)
+
+type maintenanceOptions struct {
+	client corepb.CoreRPCClient
+	name   string
+
+	// used by start
+	critical     map[string]string
+	drain        *drainNodeOptions
+	emptyTimeout time.Duration
+	fix          bool
+
+	// used by finish
+	ttl              int
+	heartbeatTimeout time.Duration
+}
+
+// start runs steps till the node is ready for maintenance,
+// steps done before are skipped, so it can be run again to resume
+func (o *maintenanceOptions) start(ctx context.Context) error {
+	m, err := loadMaintenance(o.name)
+	switch {
+	case os.IsNotExist(err):
+		node, err := o.client.GetNode(ctx, &corepb.GetNodeOptions{Nodename: o.name})
+		if err != nil {
+			return fmt.Errorf("[Maintenance] failed to get node %s: %v", o.name, err)
+		}
+		m = types.NewMaintenance(o.name, operator(), node.Bypass)
+		if err := saveMaintenance(m); err != nil {
+			return err
+		}
+		logrus.Infof("[Maintenance] Maintenance of node %s started", o.name)
+	case err != nil:
+		return err
+	default:
+		logrus.Infof("[Maintenance] Resume maintenance of node %s started by %s at %s", o.name, m.Operator, m.StartedAt.Format(time.RFC3339))
+	}
+
+	err = o.runSteps(ctx, m, "start", map[string]func(context.Context, *types.Maintenance) error{
+		types.MaintenanceCordon:   o.cordon,
+		types.MaintenanceCritical: o.checkCritical,
+		types.MaintenanceDrain:    o.runDrain,
+		types.MaintenanceWait:     o.waitEmpty,
+		types.MaintenanceResource: o.checkResource,
+	})
+	describe.Maintenance(m)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("[Maintenance] Node %s is ready for maintenance, run `node maintenance finish %s` when it's done", o.name, o.name)
+	return nil
+}
+
+// finish runs steps to bring the node back, the state is removed after all steps are done
+func (o *maintenanceOptions) finish(ctx context.Context) error {
+	m, err := loadMaintenance(o.name)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("[Maintenance] node %s is not under maintenance, start it first", o.name)
+	}
+	if err != nil {
+		return err
+	}
+	if next := m.Next(); next != nil && next.Name != types.MaintenanceWork && !m.Step(types.MaintenanceWork).Done {
+		return fmt.Errorf("[Maintenance] node %s is not ready for maintenance, step %s is not done, run start to resume", o.name, next.Name)
+	}
+
+	err = o.runSteps(ctx, m, "finish", map[string]func(context.Context, *types.Maintenance) error{
+		types.MaintenanceWork:      func(context.Context, *types.Maintenance) error { return nil },
+		types.MaintenanceUp:        o.up,
+		types.MaintenanceHeartbeat: o.verifyHeartbeat,
+		types.MaintenanceUncordon:  o.uncordon,
+	})
+	describe.Maintenance(m)
+	if err != nil {
+		return err
+	}
+
+	if err := removeMaintenance(o.name); err != nil {
+		logrus.Warnf("[Maintenance] Failed to remove state of node %s: %v", o.name, err)
+	}
+	logrus.Infof("[Maintenance] Maintenance of node %s finished", o.name)
+	return nil
+}
+
+// abort gives up the maintenance at any step, bypass of the node is restored if it's cordoned by start,
+// and the state is removed, workloads migrated are not moved back
+func (o *maintenanceOptions) abort(ctx context.Context) error {
+	m, err := loadMaintenance(o.name)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("[Maintenance] node %s is not under maintenance", o.name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// a failed cordon may still have bypassed the node, e.g. timed out after eru-core took it,
+	// restoring bypass is harmless if it didn't
+	if cordon := m.Step(types.MaintenanceCordon); (cordon.Done || cordon.Error != "") && !m.Step(types.MaintenanceUncordon).Done {
+		if err := o.uncordon(ctx, m); err != nil {
+			return fmt.Errorf("[Maintenance] failed to uncordon node %s: %v, the state is kept, run abort again", o.name, err)
+		}
+		logrus.Infof("[Maintenance] Node %s uncordoned", o.name)
+	}
+	if err := removeMaintenance(o.name); err != nil {
+		return err
+	}
+	logrus.Infof("[Maintenance] Maintenance of node %s aborted", o.name)
+	return nil
+}
+
+func (o *maintenanceOptions) status(_ context.Context) error {
+	m, err := loadMaintenance(o.name)
+	if os.IsNotExist(err) {
+		logrus.Infof("[Maintenance] Node %s is not under maintenance", o.name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	logrus.Infof("[Maintenance] Maintenance of node %s started by %s at %s", o.name, m.Operator, m.StartedAt.Format(time.RFC3339))
+	describe.Maintenance(m)
+	return nil
+}
+
+// runSteps runs the given steps in order, and saves the state after each step,
+// it stops at the first failure, the error is kept in the state
+func (o *maintenanceOptions) runSteps(ctx context.Context, m *types.Maintenance, action string, steps map[string]func(context.Context, *types.Maintenance) error) error {
+	for _, s := range m.Steps {
+		run, ok := steps[s.Name]
+		if !ok || s.Done {
+			continue
+		}
+		logrus.Infof("[Maintenance] Step %s of node %s", s.Name, o.name)
+		if err := run(ctx, m); err != nil {
+			s.Error = err.Error()
+			if err := saveMaintenance(m); err != nil {
+				logrus.Errorf("[Maintenance] Failed to save state of node %s: %v", o.name, err)
+			}
+			return fmt.Errorf("[Maintenance] step %s failed: %v, run %s again to resume", s.Name, err, action)
+		}
+		s.Done, s.DoneAt, s.Error = true, time.Now(), ""
+		if err := saveMaintenance(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *maintenanceOptions) cordon(ctx context.Context, _ *types.Maintenance) error {
+	return o.setBypass(ctx, corepb.TriOpt_TRUE)
+}
+
+// uncordon restores bypass of the node before the maintenance,
+// so a node bypassed by hand before is kept bypassed
+func (o *maintenanceOptions) uncordon(ctx context.Context, m *types.Maintenance) error {
+	if m.Bypass {
+		return o.setBypass(ctx, corepb.TriOpt_TRUE)
+	}
+	return o.setBypass(ctx, corepb.TriOpt_FALSE)
+}
+
+func (o *maintenanceOptions) setBypass(ctx context.Context, bypass corepb.TriOpt) error {
+	_, err := o.client.SetNode(ctx, &corepb.SetNodeOptions{
+		Nodename: o.name,
+		Bypass:   bypass,
+	})
+	return err
+}
+
+// checkCritical fails if any workload on the node has any of the critical labels,
+// they should be handled by hand before draining
+func (o *maintenanceOptions) checkCritical(ctx context.Context, _ *types.Maintenance) error {
+	workloads, err := o.workloads(ctx)
+	if err != nil {
+		return err
+	}
+	critical := []string{}
+	for _, w := range workloads {
+		for k, v := range o.critical {
+			if value, ok := w.Labels[k]; ok && value == v {
+				critical = append(critical, w.Name)
+				break
+			}
+		}
+	}
+	if len(critical) > 0 {
+		return fmt.Errorf("critical workloads on node: %s, move them off by hand", strings.Join(critical, ", "))
+	}
+	return nil
+}
+
+func (o *maintenanceOptions) runDrain(ctx context.Context, _ *types.Maintenance) error {
+	return o.drain.run(ctx)
+}
+
+// waitEmpty waits until no workload is on the node
+func (o *maintenanceOptions) waitEmpty(ctx context.Context, _ *types.Maintenance) error {
+	ctx, cancel := context.WithTimeout(ctx, o.emptyTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(maintenancePollInterval)
+	defer ticker.Stop()
+	for {
+		workloads, err := o.workloads(ctx)
+		if err == nil && len(workloads) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%d workload(s) still on node after %v", len(workloads), o.emptyTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkResource fails if resource of the node has diffs, unless they're fixed
+func (o *maintenanceOptions) checkResource(ctx context.Context, m *types.Maintenance) error {
+	resource, err := o.client.GetNodeResource(ctx, &corepb.GetNodeResourceOptions{
+		Opts: &corepb.GetNodeOptions{Nodename: o.name},
+		Fix:  o.fix,
+	})
+	if err != nil {
+		return err
+	}
+	m.Diffs = resource.Diffs
+	if len(resource.Diffs) == 0 {
+		return nil
+	}
+	if o.fix {
+		logrus.Warnf("[Maintenance] %d resource diff(s) of node %s fixed", len(resource.Diffs), o.name)
+		return nil
+	}
+	return fmt.Errorf("%d resource diff(s), check them by `node resource %s`, or start again with --fix", len(resource.Diffs), o.name)
+}
+
+func (o *maintenanceOptions) up(ctx context.Context, _ *types.Maintenance) error {
+	_, err := o.client.SetNodeStatus(ctx, &corepb.SetNodeStatusOptions{
+		Nodename: o.name,
+		Ttl:      int64(o.ttl),
+	})
+	return err
+}
+
+// verifyHeartbeat waits till the status set by up expires, and then waits for the node to be alive,
+// so the node is alive only if its own heartbeat works
+func (o *maintenanceOptions) verifyHeartbeat(ctx context.Context, m *types.Maintenance) error {
+	expire := time.Until(m.Step(types.MaintenanceUp).DoneAt.Add(time.Duration(o.ttl) * time.Second))
+	if expire > 0 {
+		logrus.Infof("[Maintenance] Wait %v for the status set by up to expire", expire.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(expire):
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.heartbeatTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(maintenancePollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := o.client.GetNodeStatus(ctx, &corepb.GetNodeStatusOptions{Nodename: o.name})
+		if err == nil && status.Alive {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("node is not alive after %v, check its heartbeat", o.heartbeatTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *maintenanceOptions) workloads(ctx context.Context) ([]*corepb.Workload, error) {
+	resp, err := o.client.ListNodeWorkloads(ctx, &corepb.GetNodeOptions{Nodename: o.name})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Workloads, nil
+}
+
+// operator returns who runs the maintenance, user@host
+func operator() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	return fmt.Sprintf("%s@%s", username, utils.GetHostname())
+}
+
+func saveMaintenance(m *types.Maintenance) error {
+	dir, err := utils.StateDir(maintenanceDir)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, filepath.Base(m.Nodename)+".json"), b, 0600)
+}
+
+func removeMaintenance(name string) error {
+	dir, err := utils.StateDir(maintenanceDir)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, filepath.Base(name)+".json"))
+}
+
+func loadMaintenance(name string) (*types.Maintenance, error) {
+	dir, err := utils.StateDir(maintenanceDir)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(dir, filepath.Base(name)+".json"))
+	if err != nil {
+		return nil, err
+	}
+	m := &types.Maintenance{}
+	return m, json.Unmarshal(b, m)
+}
+
+func newMaintenanceOptions(c *cli.Context) (*maintenanceOptions, error) {
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return nil, err
+	}
+
+	name := c.Args().First()
+	if name == "" {
+		return nil, errors.New("Node name must be given")
+	}
+
+	return &maintenanceOptions{
+		client:   client,
+		name:     name,
+		critical: utils.SplitEquality(c.StringSlice("critical")),
+		drain: &drainNodeOptions{
+			client:      client,
+			name:        name,
+			concurrency: c.Int("concurrency"),
+			waitTimeout: c.Duration("wait-timeout"),
+		},
+		emptyTimeout:     c.Duration("empty-timeout"),
+		fix:              c.Bool("fix"),
+		ttl:              c.Int("ttl"),
+		heartbeatTimeout: c.Duration("heartbeat-timeout"),
+	}, nil
+}
+
+func cmdNodeMaintenanceStart(c *cli.Context) error {
+	o, err := newMaintenanceOptions(c)
+	if err != nil {
+		return err
+	}
+	return o.start(c.Context)
+}
+
+// cmdNodeMaintenanceStatus only reads the local state, so it works without eru-core
+func cmdNodeMaintenanceStatus(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Node name must be given")
+	}
+	o := &maintenanceOptions{name: name}
+	return o.status(c.Context)
+}
+
+func cmdNodeMaintenanceFinish(c *cli.Context) error {
+	o, err := newMaintenanceOptions(c)
+	if err != nil {
+		return err
+	}
+	return o.finish(c.Context)
+}
+
+func cmdNodeMaintenanceAbort(c *cli.Context) error {
+	o, err := newMaintenanceOptions(c)
+	if err != nil {
+		return err
+	}
+	return o.abort(c.Context)
+}
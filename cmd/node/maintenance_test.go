@@ -0,0 +1,141 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"google.golang.org/grpc"
+)
+
+type fakeClient struct {
+	corepb.CoreRPCClient
+	bypass    bool
+	workloads []*corepb.Workload
+	setNode   []*corepb.SetNodeOptions
+	err       error
+}
+
+func (c *fakeClient) ListNodeWorkloads(_ context.Context, _ *corepb.GetNodeOptions, _ ...grpc.CallOption) (*corepb.Workloads, error) {
+	return &corepb.Workloads{Workloads: c.workloads}, c.err
+}
+
+func (c *fakeClient) GetNode(_ context.Context, in *corepb.GetNodeOptions, _ ...grpc.CallOption) (*corepb.Node, error) {
+	return &corepb.Node{Name: in.Nodename, Bypass: c.bypass}, c.err
+}
+
+func (c *fakeClient) SetNode(_ context.Context, in *corepb.SetNodeOptions, _ ...grpc.CallOption) (*corepb.Node, error) {
+	c.setNode = append(c.setNode, in)
+	return &corepb.Node{}, c.err
+}
+
+// startMaintenance saves a maintenance of node test with steps done till the given one
+func startMaintenance(t *testing.T, doneTill string, bypass bool) {
+	t.Helper()
+	m := types.NewMaintenance("test", "tonic@localhost", bypass)
+	for _, s := range m.Steps {
+		if doneTill == "" {
+			break
+		}
+		s.Done = true
+		if s.Name == doneTill {
+			break
+		}
+	}
+	if err := saveMaintenance(m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaintenanceAbort(t *testing.T) {
+	cases := []struct {
+		name     string
+		doneTill string
+		uncordon bool
+	}{
+		{"nothing done", "", false},
+		{"cordoned", types.MaintenanceCordon, true},
+		{"under maintenance", types.MaintenanceWork, true},
+		{"uncordoned", types.MaintenanceUncordon, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("ERU_CLI_HOME", t.TempDir())
+			startMaintenance(t, c.doneTill, false)
+
+			client := &fakeClient{}
+			o := &maintenanceOptions{client: client, name: "test"}
+			if err := o.abort(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if c.uncordon != (len(client.setNode) == 1 && client.setNode[0].Bypass == corepb.TriOpt_FALSE) {
+				t.Errorf("SetNode() is called with %v, uncordon should be %v", client.setNode, c.uncordon)
+			}
+			if _, err := loadMaintenance("test"); !os.IsNotExist(err) {
+				t.Errorf("state is not removed, %v", err)
+			}
+		})
+	}
+}
+
+func TestMaintenanceRestoreBypass(t *testing.T) {
+	t.Setenv("ERU_CLI_HOME", t.TempDir())
+
+	// node bypassed by hand before is kept bypassed, start stops at check-critical with the node cordoned
+	client := &fakeClient{
+		bypass:    true,
+		workloads: []*corepb.Workload{{Name: "db", Labels: map[string]string{"critical": "true"}}},
+	}
+	o := &maintenanceOptions{client: client, name: "test", critical: map[string]string{"critical": "true"}}
+	if err := o.start(context.Background()); err == nil {
+		t.Fatal("start() should fail with critical workloads")
+	}
+	m, err := loadMaintenance("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Bypass || !m.Step(types.MaintenanceCordon).Done {
+		t.Fatalf("bypass = %v, cordon = %v", m.Bypass, m.Step(types.MaintenanceCordon))
+	}
+
+	if err := o.abort(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if last := client.setNode[len(client.setNode)-1]; last.Bypass != corepb.TriOpt_TRUE {
+		t.Errorf("bypass is restored to %v, want TRUE", last.Bypass)
+	}
+}
+
+func TestMaintenanceAbortFails(t *testing.T) {
+	t.Setenv("ERU_CLI_HOME", t.TempDir())
+	o := &maintenanceOptions{client: &fakeClient{}, name: "test"}
+	if err := o.abort(context.Background()); err == nil {
+		t.Error("abort() should fail if node is not under maintenance")
+	}
+
+	// state is kept if uncordon fails, so abort can be run again
+	startMaintenance(t, types.MaintenanceDrain, false)
+	o.client = &fakeClient{err: errors.New("unavailable")}
+	if err := o.abort(context.Background()); err == nil {
+		t.Error("abort() should fail if uncordon fails")
+	}
+	if _, err := loadMaintenance("test"); err != nil {
+		t.Errorf("state is removed, %v", err)
+	}
+}
+
+func TestMaintenanceStatusWithoutCore(t *testing.T) {
+	t.Setenv("ERU_CLI_HOME", t.TempDir())
+	o := &maintenanceOptions{name: "test"}
+	if err := o.status(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	startMaintenance(t, types.MaintenanceCordon, false)
+	if err := o.status(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,39 +2,78 @@ package node
 
 import (
 	"context"
-	"io"
+	"time"
 
 	"github.com/projecteru2/cli/cmd/utils"
 	"github.com/projecteru2/cli/describe"
 	corepb "github.com/projecteru2/core/rpc/gen"
 
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 )
 
 type watchNodeStatusOptions struct {
-	client corepb.CoreRPCClient
+	client      corepb.CoreRPCClient
+	idleTimeout time.Duration
+
+	// last messages of nodes, to resync after reconnecting
+	known map[string]*corepb.NodeStatusStreamMessage
 }
 
 func (o *watchNodeStatusOptions) run(ctx context.Context) error {
+	o.known = map[string]*corepb.NodeStatusStreamMessage{}
+	w := &utils.Watcher{
+		Name:        "WatchNodeStatus",
+		Connect:     o.connect,
+		Resync:      o.resync,
+		IdleTimeout: o.idleTimeout,
+	}
+	return w.Run(ctx)
+}
+
+func (o *watchNodeStatusOptions) connect(ctx context.Context) (func() error, error) {
 	resp, err := o.client.NodeStatusStream(ctx, &corepb.Empty{})
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	for {
+	return func() error {
 		m, err := resp.Recv()
-		if err == io.EOF {
-			break
-		}
 		if err != nil {
 			return err
 		}
+		o.handle(m)
+		return nil
+	}, nil
+}
 
-		describe.NodeStatusMessage(m)
+// resync gets status of known nodes, and describes the changed ones
+func (o *watchNodeStatusOptions) resync(ctx context.Context) error {
+	changed := 0
+	for nodename, last := range o.known {
+		m, err := o.client.GetNodeStatus(ctx, &corepb.GetNodeStatusOptions{Nodename: nodename})
+		if err != nil {
+			logrus.Warnf("[WatchNodeStatus] Failed to get status of node %s: %v", nodename, err)
+			continue
+		}
+		if m.Alive != last.Alive {
+			changed++
+			o.handle(m)
+		}
 	}
+	logrus.Infof("[WatchNodeStatus] Resynced %d node(s), %d changed", len(o.known), changed)
 	return nil
 }
 
+func (o *watchNodeStatusOptions) handle(m *corepb.NodeStatusStreamMessage) {
+	if m == nil {
+		return
+	}
+	if m.Error == "" {
+		o.known[m.Nodename] = m
+	}
+	describe.NodeStatusMessage(m)
+}
+
 func cmdNodeWatchStatus(c *cli.Context) error {
 	client, err := utils.NewCoreRPCClient(c)
 	if err != nil {
@@ -42,7 +81,8 @@ func cmdNodeWatchStatus(c *cli.Context) error {
 	}
 
 	o := &watchNodeStatusOptions{
-		client: client,
+		client:      client,
+		idleTimeout: c.Duration("idle-timeout"),
 	}
 	return o.run(c.Context)
 }
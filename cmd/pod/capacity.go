@@ -29,11 +29,11 @@ type capacityPodOptions struct {
 
 func (o *capacityPodOptions) run(ctx context.Context) error {
 	cpumem := resourcetypes.RawParams{
-		"cpu":    o.cpu,
-		"memory": o.memory,
+		"cpu-request":    o.cpu,
+		"memory-request": o.memory,
 	}
 	storage := resourcetypes.RawParams{
-		"storage": o.storage,
+		"storage-request": o.storage,
 	}
 
 	if o.cpuBind {
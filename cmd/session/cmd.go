@@ -0,0 +1,35 @@
+package session
+
+import (
+	"github.com/projecteru2/cli/cmd/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Command exports session subommands
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:  "session",
+		Usage: "session recordings of exec and lambda",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "play",
+				Usage:     "replay a session recording in terminal",
+				ArgsUsage: "<file>",
+				Action:    utils.ExitCoder(cmdSessionPlay),
+				Flags: []cli.Flag{
+					&cli.Float64Flag{
+						Name:  "speed",
+						Usage: "playback speed, 2 means twice as fast",
+						Value: 1,
+					},
+					&cli.DurationFlag{
+						Name:  "max-wait",
+						Usage: "limit pauses between outputs to this duration, 0 means no limit",
+						Value: 0,
+					},
+				},
+			},
+		},
+	}
+}
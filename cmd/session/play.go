@@ -0,0 +1,99 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/projecteru2/cli/interactive"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// maxEventSize limits the size of a line in recordings
+const maxEventSize = 16 * 1024 * 1024
+
+type playSessionOptions struct {
+	file    string
+	speed   float64
+	maxWait time.Duration
+}
+
+func (o *playSessionOptions) run(ctx context.Context) error {
+	f, err := os.Open(o.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("[Play] %s is empty", o.file)
+	}
+	header := &interactive.CastHeader{}
+	if err := json.Unmarshal(scanner.Bytes(), header); err != nil {
+		return fmt.Errorf("[Play] invalid header %v", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("[Play] unsupported asciicast version %d", header.Version)
+	}
+	logrus.Infof("[Play] %s, %dx%d, recorded at %s", header.Title, header.Width, header.Height, time.Unix(header.Timestamp, 0).Format(time.RFC3339))
+
+	last := 0.0
+	for line := 2; scanner.Scan(); line++ {
+		var (
+			at         float64
+			kind, data string
+		)
+		if err := json.Unmarshal(scanner.Bytes(), &[]any{&at, &kind, &data}); err != nil {
+			return fmt.Errorf("[Play] invalid event at line %d: %v", line, err)
+		}
+		// only output is replayed, input is echoed by remote terminal anyway
+		if kind != "o" {
+			continue
+		}
+
+		wait := time.Duration((at - last) / o.speed * float64(time.Second))
+		if o.maxWait > 0 && wait > o.maxWait {
+			wait = o.maxWait
+		}
+		last = at
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		if _, err := os.Stdout.WriteString(data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func cmdSessionPlay(c *cli.Context) error {
+	file := c.Args().First()
+	if file == "" {
+		return errors.New("[Play] recording file should not be empty")
+	}
+	speed := c.Float64("speed")
+	if speed <= 0 {
+		return errors.New("[Play] speed should be positive")
+	}
+
+	o := &playSessionOptions{
+		file:    file,
+		speed:   speed,
+		maxWait: c.Duration("max-wait"),
+	}
+	return o.run(c.Context)
+}
@@ -0,0 +1,41 @@
+package spec
+
+import (
+	"github.com/projecteru2/cli/cmd/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Command exports spec subommands
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:  "spec",
+		Usage: "spec commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "lint",
+				Usage:     "lint app spec(s), exit with non-zero code if any error found",
+				ArgsUsage: "<spec file uri> [<spec file uri>...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "treat warnings as errors",
+					},
+					&cli.StringSliceFlag{
+						Name:  "values",
+						Usage: "values file to render specs as go template, can use multiple times, later ones override former ones",
+					},
+					&cli.StringSliceFlag{
+						Name:  "set",
+						Usage: "set template value, can use multiple times, overrides values files, e.g., image.tag=v1",
+					},
+					&cli.StringSliceFlag{
+						Name:  "overlay",
+						Usage: "overlay specs deep merged onto the specs, can use multiple times, e.g., prod.yaml",
+					},
+				},
+				Action: utils.ExitCoder(cmdSpecLint),
+			},
+		},
+	}
+}
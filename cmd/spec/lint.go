@@ -0,0 +1,340 @@
+package spec
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/juju/errors"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	lineRegexp    = regexp.MustCompile(`line (\d+): (.*)`)
+	portRegexp    = regexp.MustCompile(`^(\d+)(-(\d+))?$`)
+	volFlagRegexp = regexp.MustCompile(`^[rwmo]*$`)
+
+	restartPolicies = map[string]bool{
+		"":               true,
+		"no":             true,
+		"always":         true,
+		"unless-stopped": true,
+		"on-failure":     true,
+	}
+)
+
+type lintSpecsOptions struct {
+	uris       []string
+	strict     bool
+	valueFiles []string
+	sets       []string
+	overlays   []string
+}
+
+func (o *lintSpecsOptions) run() error {
+	issues := []*types.LintIssue{}
+	for _, uri := range o.uris {
+		data, err := utils.LoadSpec(uri, o.valueFiles, o.sets, o.overlays)
+		if err != nil {
+			issues = append(issues, &types.LintIssue{File: uri, Level: types.LintError, Message: err.Error()})
+			continue
+		}
+		fileIssues := lintSpecs(uri, data)
+		if len(o.overlays) > 0 {
+			// lines of merged specs don't match any file
+			for _, issue := range fileIssues {
+				issue.Line = 0
+			}
+		}
+		issues = append(issues, fileIssues...)
+	}
+
+	describe.LintIssues(issues...)
+
+	for _, issue := range issues {
+		if issue.Level == types.LintError || o.strict {
+			return cli.Exit("", 1)
+		}
+	}
+	return nil
+}
+
+func cmdSpecLint(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("[Lint] a specs must be given")
+	}
+
+	o := &lintSpecsOptions{
+		uris:       c.Args().Slice(),
+		strict:     c.Bool("strict"),
+		valueFiles: c.StringSlice("values"),
+		sets:       c.StringSlice("set"),
+		overlays:   c.StringSlice("overlay"),
+	}
+	return o.run()
+}
+
+type specLinter struct {
+	file   string
+	lines  []string
+	issues []*types.LintIssue
+}
+
+func lintSpecs(file string, data []byte) []*types.LintIssue {
+	l := &specLinter{
+		file:  file,
+		lines: strings.Split(string(data), "\n"),
+	}
+
+	// unknown keys are errors, yaml.v2 keeps decoding after type errors
+	// so we can go on checking the partially decoded specs
+	specs := &types.Specs{}
+	if err := yaml.UnmarshalStrict(data, specs); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			l.addYAMLError(err.Error())
+			return l.issues
+		}
+		for _, e := range typeErr.Errors {
+			l.addYAMLError(e)
+		}
+	}
+
+	l.lintSpecs(specs)
+	return l.issues
+}
+
+func (l *specLinter) addYAMLError(msg string) {
+	issue := &types.LintIssue{File: l.file, Level: types.LintError, Message: msg}
+	if m := lineRegexp.FindStringSubmatch(msg); m != nil {
+		issue.Line, _ = strconv.Atoi(m[1])
+		issue.Message = m[2]
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *specLinter) add(level string, path []string, format string, args ...any) {
+	l.issues = append(l.issues, &types.LintIssue{
+		File:    l.file,
+		Line:    l.lineOf(path...),
+		Path:    strings.Join(path, "."),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lineOf finds the line of a key path like entrypoints.web.publish,
+// by searching each key under the block of its parent
+func (l *specLinter) lineOf(path ...string) int {
+	line, start, indent := 0, 0, -1
+	for _, key := range path {
+		found := false
+		for i := start; i < len(l.lines); i++ {
+			trimmed := strings.TrimLeft(l.lines[i], " ")
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			current := len(l.lines[i]) - len(trimmed)
+			if i > start && current <= indent {
+				break
+			}
+			trimmed = strings.Trim(strings.TrimPrefix(trimmed, "- "), `"'`)
+			if strings.HasPrefix(trimmed, key+":") || strings.HasPrefix(trimmed, key+`":`) || strings.HasPrefix(trimmed, key+`':`) {
+				line, start, indent, found = i+1, i+1, current, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return line
+}
+
+func (l *specLinter) lintSpecs(specs *types.Specs) {
+	if specs.Appname == "" {
+		l.add(types.LintError, []string{"appname"}, "appname is empty")
+	}
+	if len(specs.Entrypoints) == 0 {
+		l.add(types.LintError, []string{"entrypoints"}, "no entrypoints defined")
+	}
+
+	names := []string{}
+	for name := range specs.Entrypoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l.lintEntrypoint(name, specs.Entrypoints[name])
+	}
+
+	for i, volume := range specs.Volumes {
+		l.lintVolume([]string{"volumes"}, i, volume)
+	}
+	for i, volume := range specs.VolumesRequest {
+		l.lintVolume([]string{"volumes_request"}, i, volume)
+	}
+	for i, host := range specs.ExtraHosts {
+		if !strings.Contains(host, ":") {
+			l.add(types.LintError, []string{"extra_hosts"}, "extra_hosts[%d] %q should be in host:ip format", i, host)
+		}
+	}
+}
+
+func (l *specLinter) lintEntrypoint(name string, entry types.Entrypoint) {
+	path := []string{"entrypoints", name}
+	sub := func(keys ...string) []string {
+		return append(append([]string{}, path...), keys...)
+	}
+
+	if strings.Contains(name, "_") {
+		l.add(types.LintError, path, "entrypoint name %q can not contain _", name)
+	}
+
+	switch {
+	case entry.Command == "" && len(entry.Commands) == 0:
+		l.add(types.LintError, path, "neither cmd nor commands is given")
+	case entry.Command != "" && len(entry.Commands) > 0:
+		l.add(types.LintWarning, sub("cmd"), "both cmd and commands are given, cmd will be ignored")
+	}
+
+	for i, port := range entry.Publish {
+		if err := checkPort(port, true); err != nil {
+			l.add(types.LintError, sub("publish"), "publish[%d] %v", i, err)
+		}
+	}
+
+	if hc := entry.HealthCheck; hc != nil {
+		if len(hc.TCPPorts) == 0 && hc.HTTPPort == "" {
+			l.add(types.LintWarning, sub("healthcheck"), "healthcheck has neither tcp_ports nor http_port, it checks nothing")
+		}
+		for i, port := range hc.TCPPorts {
+			if err := checkPort(port, false); err != nil {
+				l.add(types.LintError, sub("healthcheck", "tcp_ports"), "tcp_ports[%d] %v", i, err)
+			}
+		}
+		if hc.HTTPPort != "" {
+			if err := checkPort(hc.HTTPPort, false); err != nil {
+				l.add(types.LintError, sub("healthcheck", "http_port"), "http_port %v", err)
+			}
+		}
+		if hc.HTTPURL != "" && hc.HTTPPort == "" {
+			l.add(types.LintError, sub("healthcheck", "url"), "url is given without http_port")
+		}
+		if hc.HTTPURL != "" && !strings.HasPrefix(hc.HTTPURL, "/") {
+			l.add(types.LintError, sub("healthcheck", "url"), "url %q should be a path starting with /", hc.HTTPURL)
+		}
+		if hc.HTTPCode != 0 && (hc.HTTPCode < 100 || hc.HTTPCode > 599) {
+			l.add(types.LintError, sub("healthcheck", "code"), "code %d is not a valid http status code", hc.HTTPCode)
+		}
+	}
+
+	if hook := entry.Hook; hook != nil {
+		if len(hook.AfterStart) == 0 && len(hook.BeforeStop) == 0 {
+			l.add(types.LintWarning, sub("hook"), "hook has neither after_start nor before_stop")
+		}
+		for _, h := range []struct {
+			key  string
+			cmds []string
+		}{{"after_start", hook.AfterStart}, {"before_stop", hook.BeforeStop}} {
+			for i, cmd := range h.cmds {
+				if len(coreutils.MakeCommandLineArgs(cmd)) == 0 {
+					l.add(types.LintError, sub("hook", h.key), "%s[%d] is an empty command", h.key, i)
+				}
+			}
+		}
+	}
+
+	if d := entry.Deploy; d != nil {
+		if d.Count < 0 {
+			l.add(types.LintError, sub("deploy", "count"), "count %d should not be negative", d.Count)
+		}
+		for _, cpu := range []struct {
+			key   string
+			value float64
+		}{{"cpu", d.CPU}, {"cpu_request", d.CPURequest}, {"cpu_limit", d.CPULimit}} {
+			if cpu.value < 0 {
+				l.add(types.LintError, sub("deploy", cpu.key), "%s %v should not be negative", cpu.key, cpu.value)
+			}
+		}
+		for _, size := range []struct {
+			key, value string
+		}{
+			{"memory", d.Memory}, {"memory_request", d.MemoryRequest}, {"memory_limit", d.MemoryLimit},
+			{"storage", d.Storage}, {"storage_request", d.StorageRequest}, {"storage_limit", d.StorageLimit},
+		} {
+			if size.value == "" {
+				continue
+			}
+			if n, err := utils.ParseRAMInHuman(size.value); err != nil || n < 0 {
+				l.add(types.LintError, sub("deploy", size.key), "%s %q is not a valid size", size.key, size.value)
+			}
+		}
+	}
+
+	if entry.Restart != "" {
+		policy, retry, hasRetry := strings.Cut(entry.Restart, ":")
+		switch {
+		case !restartPolicies[policy]:
+			l.add(types.LintError, sub("restart"), "unknown restart policy %q, should be no, always, unless-stopped or on-failure[:N]", entry.Restart)
+		case hasRetry && policy != "on-failure":
+			l.add(types.LintError, sub("restart"), "only on-failure policy accepts a retry count")
+		case hasRetry:
+			if n, err := strconv.Atoi(retry); err != nil || n < 0 {
+				l.add(types.LintError, sub("restart"), "retry count %q should be a non-negative integer", retry)
+			}
+		}
+	}
+}
+
+// lintVolume checks volume in src:dst[:flags[:size]] format, e.g. AUTO:/data:rw:1G
+func (l *specLinter) lintVolume(path []string, index int, volume string) {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		l.add(types.LintError, path, "%s[%d] %q should be in src:dst[:flags[:size]] format", path[0], index, volume)
+		return
+	}
+	if parts[0] == "" {
+		l.add(types.LintError, path, "%s[%d] %q has empty source", path[0], index, volume)
+	}
+	if !filepath.IsAbs(parts[1]) {
+		l.add(types.LintError, path, "%s[%d] %q destination should be an absolute path", path[0], index, volume)
+	}
+	if len(parts) >= 3 && !volFlagRegexp.MatchString(parts[2]) {
+		l.add(types.LintError, path, "%s[%d] %q has unknown flags %q, should be combination of r, w, m, o", path[0], index, volume, parts[2])
+	}
+	if len(parts) == 4 {
+		if size, err := utils.ParseRAMInHuman(parts[3]); err != nil || size < 0 {
+			l.add(types.LintError, path, "%s[%d] %q has invalid size %q", path[0], index, volume, parts[3])
+		}
+	}
+	if parts[0] == "AUTO" && len(parts) < 4 {
+		l.add(types.LintWarning, path, "%s[%d] %q is AUTO volume without size", path[0], index, volume)
+	}
+}
+
+// checkPort checks a port, or a port range like 8000-8010 if allowRange
+func checkPort(port string, allowRange bool) error {
+	m := portRegexp.FindStringSubmatch(port)
+	if m == nil || (m[2] != "" && !allowRange) {
+		return fmt.Errorf("%q is not a valid port", port)
+	}
+	for _, p := range []string{m[1], m[3]} {
+		if p == "" {
+			continue
+		}
+		if n, _ := strconv.Atoi(p); n < 1 || n > 65535 {
+			return fmt.Errorf("%q is out of port range", port)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,39 @@ func Command() *cli.Command {
 				Name:  "label",
 				Usage: "label filter can set multiple times",
 			},
+			&cli.StringSliceFlag{
+				Name:  "exec",
+				Usage: "run this script by sh for each event, with the event as JSON on stdin, can set multiple times",
+			},
+			&cli.StringSliceFlag{
+				Name:  "webhook",
+				Usage: "post each event as JSON to this url, can set multiple times",
+			},
+			&cli.IntFlag{
+				Name:  "webhook-retries",
+				Usage: "how many times to retry a failed webhook",
+				Value: 3,
+			},
+			&cli.StringFlag{
+				Name:  "file",
+				Usage: "append each event as a line of JSON to this file",
+			},
+			&cli.StringSliceFlag{
+				Name:  "on",
+				Usage: "only send events of these kinds to sinks, can be healthy, unhealthy, stopped, expired, deleted or error, can set multiple times",
+			},
+			&cli.BoolFlag{
+				Name:  "changes-only",
+				Usage: "only send events changing the kind of a workload to sinks",
+			},
+			&cli.DurationFlag{
+				Name:  "debounce",
+				Usage: "send an event to sinks only if the workload keeps in it for this long, flapping back sends nothing",
+			},
+			&cli.DurationFlag{
+				Name:  "idle-timeout",
+				Usage: "reconnect if nothing is received for this long, 0 to disable",
+			},
 		},
 		Action: utils.ExitCoder(cmdStatus),
 	}
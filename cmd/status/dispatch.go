@@ -0,0 +1,139 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dispatcher filters status events and sends them to sinks one by one
+type dispatcher struct {
+	sinks []sink
+	// kinds of events to send, all if empty
+	kinds map[string]bool
+	// only send events changing the kind of a workload
+	changesOnly bool
+	// a kind must be kept for debounce before sent,
+	// flapping back to the last sent kind in it sends nothing
+	debounce time.Duration
+
+	mu      sync.Mutex
+	closed  bool
+	pending map[string]*time.Timer
+	last    map[string]string
+	events  chan *types.StatusEvent
+	done    chan struct{}
+}
+
+func newDispatcher(sinks []sink, kinds []string, changesOnly bool, debounce time.Duration) *dispatcher {
+	d := &dispatcher{
+		sinks:       sinks,
+		kinds:       map[string]bool{},
+		changesOnly: changesOnly,
+		debounce:    debounce,
+		last:        map[string]string{},
+		pending:     map[string]*time.Timer{},
+		events:      make(chan *types.StatusEvent, 64),
+		done:        make(chan struct{}),
+	}
+	for _, kind := range kinds {
+		d.kinds[kind] = true
+	}
+	go d.loop()
+	return d
+}
+
+// dispatch sends event now, or after debounce if no newer event of the workload comes
+func (d *dispatcher) dispatch(event *types.StatusEvent) {
+	if len(d.sinks) == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.debounce <= 0 {
+		d.enqueue(event)
+		return
+	}
+
+	if t, ok := d.pending[event.ID]; ok {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(d.debounce, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		// replaced by a newer event, or closed
+		if d.closed || d.pending[event.ID] != t {
+			return
+		}
+		delete(d.pending, event.ID)
+		d.enqueue(event)
+	})
+	d.pending[event.ID] = t
+}
+
+// enqueue never blocks, so slow sinks won't hold d.mu and block the status stream,
+// events are dropped if the queue is full, d.mu must be held
+func (d *dispatcher) enqueue(event *types.StatusEvent) {
+	if d.closed {
+		return
+	}
+	select {
+	case d.events <- event:
+	default:
+		logrus.Warnf("[Status] Sinks are too slow, drop %s event of %s", event.Kind, event.ID)
+	}
+}
+
+func (d *dispatcher) loop() {
+	defer close(d.done)
+	for event := range d.events {
+		last, seen := d.last[event.ID]
+		if event.Kind == types.StatusEventDeleted {
+			delete(d.last, event.ID)
+		} else {
+			d.last[event.ID] = event.Kind
+		}
+		if (d.changesOnly || d.debounce > 0) && seen && last == event.Kind {
+			continue
+		}
+		if len(d.kinds) > 0 && !d.kinds[event.Kind] {
+			continue
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			logrus.Errorf("[Status] Failed to marshal event of %s: %v", event.ID, err)
+			continue
+		}
+		for _, s := range d.sinks {
+			if err := s.send(context.Background(), data); err != nil {
+				logrus.Errorf("[Status] Failed to send event of %s to %s: %v", event.ID, s, err)
+			}
+		}
+	}
+}
+
+// close drops events still in debounce, waits for sent ones and closes sinks
+func (d *dispatcher) close() {
+	d.mu.Lock()
+	d.closed = true
+	for id, t := range d.pending {
+		t.Stop()
+		delete(d.pending, id)
+	}
+	d.mu.Unlock()
+
+	close(d.events)
+	<-d.done
+	for _, s := range d.sinks {
+		if err := s.close(); err != nil {
+			logrus.Errorf("[Status] Failed to close %s: %v", s, err)
+		}
+	}
+}
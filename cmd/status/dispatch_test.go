@@ -0,0 +1,145 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/cli/types"
+)
+
+type fakeSink struct {
+	mu     sync.Mutex
+	events []*types.StatusEvent
+	block  chan struct{}
+}
+
+func (s *fakeSink) send(_ context.Context, data []byte) error {
+	if s.block != nil {
+		<-s.block
+	}
+	event := &types.StatusEvent{}
+	if err := json.Unmarshal(data, event); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *fakeSink) close() error { return nil }
+
+func (s *fakeSink) String() string { return "fake" }
+
+func (s *fakeSink) kinds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kinds := []string{}
+	for _, e := range s.events {
+		kinds = append(kinds, e.ID+":"+e.Kind)
+	}
+	return kinds
+}
+
+func event(id, kind string) *types.StatusEvent {
+	return &types.StatusEvent{ID: id, Kind: kind}
+}
+
+func assertKinds(t *testing.T, got []string, expected ...string) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("sent %v, want %v", got, expected)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("sent %v, want %v", got, expected)
+		}
+	}
+}
+
+func TestDispatchFilters(t *testing.T) {
+	s := &fakeSink{}
+	d := newDispatcher([]sink{s}, []string{types.StatusEventUnhealthy, types.StatusEventHealthy}, true, 0)
+	for _, e := range []*types.StatusEvent{
+		event("a", types.StatusEventHealthy),
+		event("a", types.StatusEventHealthy),
+		event("a", types.StatusEventStopped),
+		event("a", types.StatusEventUnhealthy),
+		event("b", types.StatusEventUnhealthy),
+		event("a", types.StatusEventUnhealthy),
+	} {
+		d.dispatch(e)
+	}
+	d.close()
+	assertKinds(t, s.kinds(), "a:healthy", "a:unhealthy", "b:unhealthy")
+}
+
+func TestDispatchDebounce(t *testing.T) {
+	debounce := 50 * time.Millisecond
+	s := &fakeSink{}
+	d := newDispatcher([]sink{s}, nil, false, debounce)
+	defer d.close()
+
+	// only the last kind kept for debounce is sent
+	d.dispatch(event("a", types.StatusEventHealthy))
+	d.dispatch(event("a", types.StatusEventUnhealthy))
+	d.dispatch(event("a", types.StatusEventStopped))
+	time.Sleep(3 * debounce)
+	assertKinds(t, s.kinds(), "a:stopped")
+
+	// flapping back to the kind sent last time sends nothing
+	d.dispatch(event("a", types.StatusEventHealthy))
+	time.Sleep(debounce / 5)
+	d.dispatch(event("a", types.StatusEventStopped))
+	time.Sleep(3 * debounce)
+	assertKinds(t, s.kinds(), "a:stopped")
+
+	// workloads are debounced separately
+	d.dispatch(event("a", types.StatusEventHealthy))
+	d.dispatch(event("b", types.StatusEventUnhealthy))
+	time.Sleep(3 * debounce)
+	kinds := s.kinds()
+	if len(kinds) != 3 {
+		t.Fatalf("sent %v", kinds)
+	}
+}
+
+func TestDispatchCloseDropsPending(t *testing.T) {
+	s := &fakeSink{}
+	d := newDispatcher([]sink{s}, nil, false, time.Hour)
+	d.dispatch(event("a", types.StatusEventHealthy))
+	d.close()
+	assertKinds(t, s.kinds())
+}
+
+// slow sinks must not block dispatching, events are dropped instead
+func TestDispatchSlowSink(t *testing.T) {
+	for _, debounce := range []time.Duration{0, time.Millisecond} {
+		s := &fakeSink{block: make(chan struct{})}
+		d := newDispatcher([]sink{s}, nil, false, debounce)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 200; i++ {
+				d.dispatch(event(string(rune('a'+i%26))+string(rune('a'+i/26)), types.StatusEventUnhealthy))
+			}
+			time.Sleep(20 * time.Millisecond)
+			// dispatching is still not blocked after timers fired
+			d.dispatch(event("last", types.StatusEventHealthy))
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("dispatch is blocked by slow sink, debounce %v", debounce)
+		}
+		close(s.block)
+		d.close()
+		if n := len(s.kinds()); n == 0 || n >= 200 {
+			t.Errorf("sent %d events, some should be dropped", n)
+		}
+	}
+}
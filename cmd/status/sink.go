@@ -0,0 +1,128 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// sinkTimeout limits how long a sink can take for an event
+	sinkTimeout = 30 * time.Second
+	// webhookBackoff is the wait before the first retry, doubled for each retry
+	webhookBackoff = time.Second
+)
+
+// sink receives status events as JSON
+type sink interface {
+	send(ctx context.Context, data []byte) error
+	close() error
+	String() string
+}
+
+// execSink runs script by sh with the event as JSON on stdin
+type execSink struct {
+	script string
+}
+
+func (s *execSink) send(ctx context.Context, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.script)
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(out)); out != "" {
+			return fmt.Errorf("%v: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *execSink) close() error {
+	return nil
+}
+
+func (s *execSink) String() string {
+	return "exec " + s.script
+}
+
+// webhookSink posts the event as JSON to url, retries on errors and non 2xx responses
+type webhookSink struct {
+	url     string
+	retries int
+	client  *http.Client
+}
+
+func (s *webhookSink) send(ctx context.Context, data []byte) (err error) {
+	backoff := webhookBackoff
+	for i := 0; ; i++ {
+		if err = s.post(ctx, data); err == nil || i >= s.retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *webhookSink) post(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *webhookSink) close() error {
+	return nil
+}
+
+func (s *webhookSink) String() string {
+	return "webhook " + s.url
+}
+
+// fileSink appends events to a file as NDJSON
+type fileSink struct {
+	file *os.File
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: f}, nil
+}
+
+func (s *fileSink) send(_ context.Context, data []byte) error {
+	_, err := s.file.Write(append(data, '\n'))
+	return err
+}
+
+func (s *fileSink) close() error {
+	return s.file.Close()
+}
+
+func (s *fileSink) String() string {
+	return "file " + s.file.Name()
+}
@@ -2,75 +2,201 @@ package status
 
 import (
 	"context"
-	"io"
+	"fmt"
+	"net/http"
 	"syscall"
+	"time"
 
 	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/types"
 	corepb "github.com/projecteru2/core/rpc/gen"
 	coreutils "github.com/projecteru2/core/utils"
 
 	"github.com/sethvargo/go-signalcontext"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
 type statusOptions struct {
-	client corepb.CoreRPCClient
-	name   string
-	entry  string
-	node   string
-	labels map[string]string
+	client      corepb.CoreRPCClient
+	name        string
+	entry       string
+	node        string
+	labels      map[string]string
+	dispatcher  *dispatcher
+	idleTimeout time.Duration
+
+	// last messages of workloads, to resync after reconnecting
+	known map[string]*corepb.WorkloadStatusStreamMessage
 }
 
 func (o *statusOptions) run(ctx context.Context) error {
 	sigCtx, cancel := signalcontext.Wrap(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
+	defer o.dispatcher.close()
+
+	o.known = map[string]*corepb.WorkloadStatusStreamMessage{}
+	w := &utils.Watcher{
+		Name:        "Status",
+		Connect:     o.connect,
+		Resync:      o.resync,
+		IdleTimeout: o.idleTimeout,
+	}
+	return w.Run(sigCtx)
+}
 
-	resp, err := o.client.WorkloadStatusStream(sigCtx, &corepb.WorkloadStatusStreamOptions{
+func (o *statusOptions) connect(ctx context.Context) (func() error, error) {
+	resp, err := o.client.WorkloadStatusStream(ctx, &corepb.WorkloadStatusStreamOptions{
 		Appname:    o.name,
 		Entrypoint: o.entry,
 		Nodename:   o.node,
 		Labels:     o.labels,
 	})
-	if err != nil || resp == nil {
-		return cli.Exit("", -1)
+	if err != nil {
+		return nil, err
 	}
-
-	for {
+	return func() error {
 		msg, err := resp.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil || msg == nil {
-			return cli.Exit("", -1)
+		if err != nil {
+			return err
 		}
+		o.handle(msg)
+		return nil
+	}, nil
+}
 
-		if msg.Error != "" {
-			if msg.Delete {
-				logrus.Warnf("%s deleted", coreutils.ShortID(msg.Id))
-			} else {
-				logrus.Errorf("[%s] status changed with error %v", coreutils.ShortID(msg.Id), msg.Error)
-			}
+// resync gets status of known workloads one by one, and handles the changed ones as messages,
+// workloads without status are expired, and those can't be found any more are deleted
+func (o *statusOptions) resync(ctx context.Context) error {
+	changed := 0
+	for id, last := range o.known {
+		msg, err := o.resyncOne(ctx, id, last)
+		if err != nil {
+			return err
+		}
+		if msg == nil {
 			continue
 		}
+		changed++
+		o.handle(msg)
+	}
+	logrus.Infof("[Status] Resynced %d workload(s), %d changed", len(o.known), changed)
+	return nil
+}
+
+// resyncOne returns the message to handle for a known workload, nil if nothing changed
+func (o *statusOptions) resyncOne(ctx context.Context, id string, last *corepb.WorkloadStatusStreamMessage) (*corepb.WorkloadStatusStreamMessage, error) {
+	resp, err := o.client.GetWorkloadsStatus(ctx, &corepb.WorkloadIDs{IDs: []string{id}})
+	if err != nil && unreachable(err) {
+		return nil, err
+	}
+	if err == nil && len(resp.Status) > 0 && resp.Status[0].Id == id {
+		status := resp.Status[0]
+		if !last.Delete && last.Status != nil && last.Status.Running == status.Running && last.Status.Healthy == status.Healthy {
+			return nil, nil
+		}
+		return &corepb.WorkloadStatusStreamMessage{Id: id, Workload: last.Workload, Status: status}, nil
+	}
+
+	// no status, the workload is either expired or deleted
+	workload, err := o.client.GetWorkload(ctx, &corepb.WorkloadID{Id: id})
+	switch {
+	case err != nil && unreachable(err):
+		return nil, err
+	case err != nil:
+		return &corepb.WorkloadStatusStreamMessage{Id: id, Workload: last.Workload, Error: err.Error(), Delete: true}, nil
+	case last.Delete:
+		// expiration is handled already
+		return nil, nil
+	default:
+		return &corepb.WorkloadStatusStreamMessage{Id: id, Workload: workload, Status: last.Status, Delete: true}, nil
+	}
+}
+
+// unreachable tells if the error is of eru-core connection instead of the workload,
+// eru-core wraps errors of workloads with its own codes
+func unreachable(err error) bool {
+	switch grpcstatus.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return true
+	default:
+		return false
+	}
+}
 
+func (o *statusOptions) handle(msg *corepb.WorkloadStatusStreamMessage) {
+	if msg == nil {
+		return
+	}
+	o.dispatcher.dispatch(newStatusEvent(msg))
+
+	if msg.Error != "" {
 		if msg.Delete {
-			logrus.Warnf("[%s] %s status expired", coreutils.ShortID(msg.Id), msg.Workload.Name)
+			delete(o.known, msg.Id)
+			logrus.Warnf("%s deleted", coreutils.ShortID(msg.Id))
+		} else {
+			logrus.Errorf("[%s] status changed with error %v", coreutils.ShortID(msg.Id), msg.Error)
 		}
+		return
+	}
+	if msg.Workload == nil || msg.Status == nil {
+		return
+	}
+	o.known[msg.Id] = msg
+
+	if msg.Delete {
+		logrus.Warnf("[%s] %s status expired", coreutils.ShortID(msg.Id), msg.Workload.Name)
+	}
 
-		switch {
-		case !msg.Status.Running:
-			logrus.Warnf("[%s] %s on %s is stopped", coreutils.ShortID(msg.Id), msg.Workload.Name, msg.Workload.Nodename)
-		case !msg.Status.Healthy:
-			logrus.Warnf("[%s] %s on %s is unhealthy", coreutils.ShortID(msg.Id), msg.Workload.Name, msg.Workload.Nodename)
-		case msg.Status.Running && msg.Status.Healthy:
-			logrus.Infof("[%s] %s back to life", coreutils.ShortID(msg.Workload.Id), msg.Workload.Name)
-			for networkName, addrs := range msg.Workload.Publish {
-				logrus.Infof("[%s] published at %s bind %v", coreutils.ShortID(msg.Id), networkName, addrs)
-			}
+	switch {
+	case !msg.Status.Running:
+		logrus.Warnf("[%s] %s on %s is stopped", coreutils.ShortID(msg.Id), msg.Workload.Name, msg.Workload.Nodename)
+	case !msg.Status.Healthy:
+		logrus.Warnf("[%s] %s on %s is unhealthy", coreutils.ShortID(msg.Id), msg.Workload.Name, msg.Workload.Nodename)
+	case msg.Status.Running && msg.Status.Healthy:
+		logrus.Infof("[%s] %s back to life", coreutils.ShortID(msg.Workload.Id), msg.Workload.Name)
+		for networkName, addrs := range msg.Workload.Publish {
+			logrus.Infof("[%s] published at %s bind %v", coreutils.ShortID(msg.Id), networkName, addrs)
 		}
 	}
-	return nil
+}
+
+// newStatusEvent converts a message of status stream to event for sinks,
+// kinds are the same as what's logged
+func newStatusEvent(msg *corepb.WorkloadStatusStreamMessage) *types.StatusEvent {
+	event := &types.StatusEvent{
+		Time:  time.Now(),
+		ID:    msg.Id,
+		Error: msg.Error,
+	}
+	if msg.Workload != nil {
+		event.Name = msg.Workload.Name
+		event.Podname = msg.Workload.Podname
+		event.Nodename = msg.Workload.Nodename
+		event.Publish = msg.Workload.Publish
+	}
+	if msg.Status != nil {
+		event.Running = msg.Status.Running
+		event.Healthy = msg.Status.Healthy
+	}
+
+	switch {
+	case msg.Error != "" && msg.Delete:
+		event.Kind = types.StatusEventDeleted
+	case msg.Error != "":
+		event.Kind = types.StatusEventError
+	case msg.Delete:
+		event.Kind = types.StatusEventExpired
+	case !event.Running:
+		event.Kind = types.StatusEventStopped
+	case !event.Healthy:
+		event.Kind = types.StatusEventUnhealthy
+	default:
+		event.Kind = types.StatusEventHealthy
+	}
+	return event
 }
 
 func cmdStatus(c *cli.Context) error {
@@ -79,12 +205,43 @@ func cmdStatus(c *cli.Context) error {
 		return err
 	}
 
+	kinds := c.StringSlice("on")
+	for _, kind := range kinds {
+		switch kind {
+		case types.StatusEventHealthy, types.StatusEventUnhealthy, types.StatusEventStopped,
+			types.StatusEventExpired, types.StatusEventDeleted, types.StatusEventError:
+		default:
+			return fmt.Errorf("invalid event kind %s", kind)
+		}
+	}
+
+	sinks := []sink{}
+	for _, script := range c.StringSlice("exec") {
+		sinks = append(sinks, &execSink{script: script})
+	}
+	for _, url := range c.StringSlice("webhook") {
+		sinks = append(sinks, &webhookSink{
+			url:     url,
+			retries: c.Int("webhook-retries"),
+			client:  &http.Client{Timeout: sinkTimeout},
+		})
+	}
+	if path := c.String("file"); path != "" {
+		s, err := newFileSink(path)
+		if err != nil {
+			return fmt.Errorf("[Status] failed to open %s: %v", path, err)
+		}
+		sinks = append(sinks, s)
+	}
+
 	o := &statusOptions{
-		client: client,
-		name:   c.Args().First(),
-		entry:  c.String("entry"),
-		node:   c.String("node"),
-		labels: utils.SplitEquality(c.StringSlice("label")),
+		client:      client,
+		name:        c.Args().First(),
+		entry:       c.String("entry"),
+		node:        c.String("node"),
+		labels:      utils.SplitEquality(c.StringSlice("label")),
+		dispatcher:  newDispatcher(sinks, kinds, c.Bool("changes-only"), c.Duration("debounce")),
+		idleTimeout: c.Duration("idle-timeout"),
 	}
 	return o.run(c.Context)
 }
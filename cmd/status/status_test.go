@@ -0,0 +1,128 @@
+package status
+
+import (
+	"context"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+type fakeClient struct {
+	corepb.CoreRPCClient
+	status    map[string]*corepb.WorkloadStatus
+	workloads map[string]*corepb.Workload
+}
+
+// GetWorkloadsStatus fails on the first ID without status, like eru-core
+func (c *fakeClient) GetWorkloadsStatus(_ context.Context, in *corepb.WorkloadIDs, _ ...grpc.CallOption) (*corepb.WorkloadsStatus, error) {
+	resp := &corepb.WorkloadsStatus{}
+	for _, id := range in.IDs {
+		status, ok := c.status[id]
+		if !ok {
+			return nil, grpcstatus.Error(1051, "key not exists")
+		}
+		resp.Status = append(resp.Status, status)
+	}
+	return resp, nil
+}
+
+func (c *fakeClient) GetWorkload(_ context.Context, in *corepb.WorkloadID, _ ...grpc.CallOption) (*corepb.Workload, error) {
+	workload, ok := c.workloads[in.Id]
+	if !ok {
+		return nil, grpcstatus.Error(1061, "workload not exists")
+	}
+	return workload, nil
+}
+
+func knownMessage(id string, running, healthy bool) *corepb.WorkloadStatusStreamMessage {
+	return &corepb.WorkloadStatusStreamMessage{
+		Id:       id,
+		Workload: &corepb.Workload{Id: id, Name: "app_" + id},
+		Status:   &corepb.WorkloadStatus{Id: id, Running: running, Healthy: healthy},
+	}
+}
+
+func TestResync(t *testing.T) {
+	client := &fakeClient{
+		status: map[string]*corepb.WorkloadStatus{
+			"same":    {Id: "same", Running: true, Healthy: true},
+			"changed": {Id: "changed", Running: true, Healthy: false},
+		},
+		workloads: map[string]*corepb.Workload{
+			"expired": {Id: "expired", Name: "app_expired"},
+		},
+	}
+	s := &fakeSink{}
+	o := &statusOptions{
+		client:     client,
+		dispatcher: newDispatcher([]sink{s}, nil, false, 0),
+		known: map[string]*corepb.WorkloadStatusStreamMessage{
+			"same":    knownMessage("same", true, true),
+			"changed": knownMessage("changed", true, true),
+			"expired": knownMessage("expired", true, true),
+			"deleted": knownMessage("deleted", true, true),
+		},
+	}
+	if err := o.resync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// expiration is reported only once
+	if err := o.resync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	o.dispatcher.close()
+
+	kinds := map[string][]string{}
+	for _, e := range s.events {
+		kinds[e.ID] = append(kinds[e.ID], e.Kind)
+	}
+	expected := map[string][]string{
+		"changed": {types.StatusEventUnhealthy},
+		"expired": {types.StatusEventExpired},
+		"deleted": {types.StatusEventDeleted},
+	}
+	if len(kinds) != len(expected) {
+		t.Fatalf("sent %v, want %v", kinds, expected)
+	}
+	for id, k := range expected {
+		if len(kinds[id]) != len(k) || kinds[id][0] != k[0] {
+			t.Errorf("sent %v for %s, want %v", kinds[id], id, k)
+		}
+	}
+	if _, ok := o.known["deleted"]; ok {
+		t.Error("deleted workload is still known")
+	}
+	if _, ok := o.known["expired"]; !ok {
+		t.Error("expired workload is not known")
+	}
+}
+
+type unreachableClient struct {
+	corepb.CoreRPCClient
+}
+
+func (c *unreachableClient) GetWorkloadsStatus(context.Context, *corepb.WorkloadIDs, ...grpc.CallOption) (*corepb.WorkloadsStatus, error) {
+	return nil, grpcstatus.Error(codes.Unavailable, "connection refused")
+}
+
+func TestResyncUnreachable(t *testing.T) {
+	s := &fakeSink{}
+	o := &statusOptions{
+		client:     &unreachableClient{},
+		dispatcher: newDispatcher([]sink{s}, nil, false, 0),
+		known:      map[string]*corepb.WorkloadStatusStreamMessage{"a": knownMessage("a", true, true)},
+	}
+	err := o.resync(context.Background())
+	o.dispatcher.close()
+	if grpcstatus.Code(err) != codes.Unavailable {
+		t.Errorf("resync() = %v, want unavailable", err)
+	}
+	if len(s.events) != 0 || len(o.known) != 1 {
+		t.Errorf("unreachable eru-core is taken as workload changes, sent %v", s.kinds())
+	}
+}
@@ -30,7 +30,7 @@ func ReadAllFiles(files []string) map[string]*types.LinuxFile {
 			if err != nil {
 				break
 			}
-			gid, err = strconv.ParseInt(ps[3], 10, 0)
+			gid, err = strconv.ParseInt(ps[4], 10, 0)
 			if err != nil {
 				break
 			}
@@ -96,3 +96,11 @@ func GetSpecFromRemote(uri string) ([]byte, error) {
 	defer resp.Body.Close()
 	return ioutil.ReadAll(resp.Body)
 }
+
+// GetSpecData reads specs from a remote URL or a local file
+func GetSpecData(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "http") {
+		return GetSpecFromRemote(uri)
+	}
+	return ioutil.ReadFile(uri)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/go-units"
+	"golang.org/x/sys/unix"
+)
+
+// Progress counts bytes written to it and shows progress with throughput on stderr,
+// it's only shown when stderr is a terminal
+type Progress struct {
+	name    string
+	total   int64
+	current int64
+	start   time.Time
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewProgress starts showing progress of name, total is the size in bytes
+func NewProgress(name string, total int64) *Progress {
+	p := &Progress{
+		name:    name,
+		total:   total,
+		start:   time.Now(),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go func() {
+		defer close(p.stopped)
+		if _, err := unix.IoctlGetTermios(int(os.Stderr.Fd()), unix.TCGETS); err != nil {
+			<-p.done
+			return
+		}
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				p.print(os.Stderr)
+				fmt.Fprintln(os.Stderr)
+				return
+			case <-ticker.C:
+				p.print(os.Stderr)
+			}
+		}
+	}()
+	return p
+}
+
+func (p *Progress) Write(b []byte) (int, error) {
+	atomic.AddInt64(&p.current, int64(len(b)))
+	return len(b), nil
+}
+
+// Done stops showing progress, returns the throughput in bytes per second
+func (p *Progress) Done() float64 {
+	close(p.done)
+	<-p.stopped
+	return p.throughput()
+}
+
+func (p *Progress) throughput() float64 {
+	elapsed := time.Since(p.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&p.current)) / elapsed
+}
+
+func (p *Progress) print(w io.Writer) {
+	current := atomic.LoadInt64(&p.current)
+	percent := 100.0
+	if p.total > 0 {
+		percent = float64(current) * 100 / float64(p.total)
+	}
+	width := 30
+	filled := int(percent) * width / 100
+	bar := make([]byte, width)
+	for i := range bar {
+		bar[i] = '-'
+		if i < filled {
+			bar[i] = '='
+		}
+	}
+	fmt.Fprintf(w, "\r%s [%s] %5.1f%% %s/%s %s/s ", p.name, bar, percent,
+		units.BytesSize(float64(current)), units.BytesSize(float64(p.total)), units.BytesSize(p.throughput()))
+}
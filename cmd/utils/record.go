@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/projecteru2/cli/interactive"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// NewSessionRecorder returns a recorder for an interactive session if --record-session is set,
+// returns nil for sessions not interactive, or an error if --require-session-record is set without it
+func NewSessionRecorder(c *cli.Context, open bool, command string) (*interactive.Recorder, error) {
+	if !open {
+		return nil, nil
+	}
+	path := c.String("record-session")
+	if path == "" {
+		if c.Bool("require-session-record") {
+			return nil, fmt.Errorf("interactive sessions must be recorded, use --record-session to set the recording file")
+		}
+		return nil, nil
+	}
+	title := fmt.Sprintf("%s on %s", c.Command.HelpName, c.String("eru"))
+	recorder, err := interactive.NewRecorder(path, title, command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session recording: %v", err)
+	}
+	return recorder, nil
+}
+
+// SaveSessionRecording closes the recorder and logs where the session is recorded
+func SaveSessionRecording(recorder *interactive.Recorder) {
+	if recorder == nil {
+		return
+	}
+	if err := recorder.Close(); err != nil {
+		logrus.Errorf("[Session] Failed to save recording %s: %v", recorder.Path(), err)
+		return
+	}
+	logrus.Infof("[Session] Recorded to %s", recorder.Path())
+}
@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coretypes "github.com/projecteru2/core/types"
+	coreutils "github.com/projecteru2/core/utils"
+)
+
+// labels and env set by eru-core and docker, they're not given when deploying
+var (
+	coreLabels   = map[string]bool{"ERU": true, "ERU_META": true, "eru.coreid": true, "eru.nodename": true}
+	coreEnvNames = map[string]bool{"APP_NAME": true, "HOSTNAME": true, "HOME": true, "PATH": true}
+)
+
+// IsCoreLabel returns if the label is set by eru-core
+func IsCoreLabel(key string) bool {
+	return coreLabels[key]
+}
+
+// DeployedEnv returns env given when deploying,
+// env set by eru-core are excluded, but env of image can't be told apart
+func DeployedEnv(env []string) []string {
+	deployed := []string{}
+	for _, e := range env {
+		name := strings.SplitN(e, "=", 2)[0]
+		if strings.HasPrefix(name, "ERU_") || coreEnvNames[name] {
+			continue
+		}
+		deployed = append(deployed, e)
+	}
+	return deployed
+}
+
+// WorkloadEntrypointLabel decodes the entrypoint label stamped by cli, nil if w doesn't have it
+func WorkloadEntrypointLabel(w *corepb.Workload) (*types.EntrypointLabel, error) {
+	l := w.Labels[types.LabelEntrypoint]
+	if l == "" {
+		return nil, nil
+	}
+	label := &types.EntrypointLabel{}
+	if err := json.Unmarshal([]byte(l), label); err != nil {
+		return nil, fmt.Errorf("invalid label %s of %s: %v", types.LabelEntrypoint, w.Name, err)
+	}
+	return label, nil
+}
+
+// WorkloadMeta decodes the ERU_META label, where eru-core keeps publish and healthcheck,
+// nil if w doesn't have it
+func WorkloadMeta(w *corepb.Workload) (*coretypes.LabelMeta, error) {
+	meta := w.Labels["ERU_META"]
+	if meta == "" {
+		return nil, nil
+	}
+	m := &coretypes.LabelMeta{}
+	if err := json.Unmarshal([]byte(meta), m); err != nil {
+		return nil, fmt.Errorf("invalid label ERU_META of %s: %v", w.Name, err)
+	}
+	return m, nil
+}
+
+// RedeployOptions rebuilds options to deploy one more workload the same as w, with the same image, env,
+// resources, networks and labels. Entrypoint options are from labels, so w must be deployed by cli with
+// the entrypoint label, and networks are from status, so w must have status with networks.
+// Files shipped when deploying are not included, fetch them by RedeployFiles
+func RedeployOptions(w *corepb.Workload) (*corepb.DeployOptions, error) {
+	appname, _, _, err := coreutils.ParseWorkloadName(w.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	entrypoint, label, err := RedeployEntrypoint(w)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := types.ParseWorkloadResources(w.Resources)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resources of %s: %v", w.Name, err)
+	}
+
+	labels := map[string]string{}
+	for k, v := range w.Labels {
+		if !IsCoreLabel(k) {
+			labels[k] = v
+		}
+	}
+
+	if w.Status == nil || len(w.Status.Networks) == 0 {
+		return nil, fmt.Errorf("%s has no status of networks, networks to join are unknown", w.Name)
+	}
+	networks := map[string]string{}
+	for network := range w.Status.Networks {
+		networks[network] = ""
+	}
+
+	return &corepb.DeployOptions{
+		Name:           appname,
+		Entrypoint:     entrypoint,
+		Resources:      resources.ToDeployResources(),
+		Podname:        w.Podname,
+		NodeFilter:     &corepb.NodeFilter{},
+		Image:          w.Image,
+		Count:          1,
+		Env:            DeployedEnv(w.Env),
+		Networks:       networks,
+		Labels:         labels,
+		Dns:            label.DNS,
+		ExtraHosts:     label.ExtraHosts,
+		DeployStrategy: corepb.DeployOptions_AUTO,
+		User:           label.User,
+	}, nil
+}
+
+// RedeployEntrypoint rebuilds entrypoint options of w from its entrypoint label and ERU_META,
+// the label is returned too for options out of entrypoint, it fails if w has no entrypoint label
+func RedeployEntrypoint(w *corepb.Workload) (*corepb.EntrypointOptions, *types.EntrypointLabel, error) {
+	_, entry, _, err := coreutils.ParseWorkloadName(w.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	label, err := WorkloadEntrypointLabel(w)
+	if err != nil {
+		return nil, nil, err
+	}
+	if label == nil {
+		return nil, nil, fmt.Errorf("%s has no label %s, it's deployed by older cli and commands are unknown, replace it with this version of cli first", w.Name, types.LabelEntrypoint)
+	}
+
+	entrypoint := &corepb.EntrypointOptions{
+		Name:       entry,
+		Commands:   label.Commands,
+		Privileged: w.Privileged,
+		Dir:        label.Dir,
+		Restart:    label.Restart,
+		Sysctls:    label.Sysctls,
+	}
+	if label.Hook != nil {
+		entrypoint.Hook = &corepb.HookOptions{
+			AfterStart: label.Hook.AfterStart,
+			BeforeStop: label.Hook.BeforeStop,
+			Force:      label.Hook.Force,
+		}
+	}
+	if label.Log != nil {
+		entrypoint.Log = &corepb.LogOptions{
+			Type:   label.Log.Type,
+			Config: label.Log.Config,
+		}
+	}
+	m, err := WorkloadMeta(w)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m != nil {
+		entrypoint.Publish = m.Publish
+		if hc := m.HealthCheck; hc != nil {
+			entrypoint.Healthcheck = &corepb.HealthCheckOptions{
+				TcpPorts: hc.TCPPorts,
+				HttpPort: hc.HTTPPort,
+				Url:      hc.HTTPURL,
+				Code:     int32(hc.HTTPCode),
+			}
+		}
+	}
+
+	return entrypoint, label, nil
+}
+
+// RedeployFiles fetches files shipped to w when deploying from w itself, with their modes and owners,
+// they're not in options of RedeployOptions. What they're now is fetched, they may be changed since deploying
+func RedeployFiles(ctx context.Context, client corepb.CoreRPCClient, w *corepb.Workload) (map[string][]byte, map[string]*corepb.FileMode, map[string]*corepb.FileOwner, error) {
+	label, err := WorkloadEntrypointLabel(w)
+	if err != nil || label == nil || len(label.Files) == 0 {
+		return nil, nil, nil, err
+	}
+
+	resp, err := client.Copy(ctx, &corepb.CopyOptions{
+		Targets: map[string]*corepb.CopyPaths{w.Id: {Paths: label.Files}},
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tarballs := map[string]*bytes.Buffer{}
+	for {
+		msg, err := resp.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if msg.Error != "" {
+			return nil, nil, nil, fmt.Errorf("copy %s of %s failed %s", msg.Path, w.Name, msg.Error)
+		}
+		if tarballs[msg.Path] == nil {
+			tarballs[msg.Path] = &bytes.Buffer{}
+		}
+		tarballs[msg.Path].Write(msg.Data)
+	}
+
+	data := map[string][]byte{}
+	modes := map[string]*corepb.FileMode{}
+	owners := map[string]*corepb.FileOwner{}
+	for _, path := range label.Files {
+		buf, ok := tarballs[path]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("%s of %s is not copied", path, w.Name)
+		}
+		header, content, err := regularFile(buf)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("copy %s of %s failed %v", path, w.Name, err)
+		}
+		data[path] = content
+		modes[path] = &corepb.FileMode{Mode: header.Mode}
+		owners[path] = &corepb.FileOwner{Uid: int32(header.Uid), Gid: int32(header.Gid)}
+	}
+	return data, modes, owners, nil
+}
+
+// regularFile returns the first regular file in the tarball
+func regularFile(r io.Reader) (*tar.Header, []byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil, errors.New("no regular file found")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		return header, content, err
+	}
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"google.golang.org/grpc"
+)
+
+func redeployWorkload() *corepb.Workload {
+	return &corepb.Workload{
+		Name:    "test_http_RfKuXJ",
+		Podname: "muroq",
+		Image:   "python:3.10",
+		Env:     []string{"APP_NAME=test", "ERU_POD=muroq", "DEBUG=1"},
+		Labels: map[string]string{
+			"ERU":                 "1",
+			"ERU_META":            `{"Publish":["8000"],"HealthCheck":{"TCPPorts":["8000"]}}`,
+			"owner":               "tonic",
+			types.LabelEntrypoint: `{"commands":["python3 -m http.server"],"dns":["8.8.8.8"]}`,
+		},
+		Resources: `{"cpumem": {"cpu_request": 1, "memory_request": 1024}}`,
+		Status:    &corepb.WorkloadStatus{Networks: map[string]string{"host": "10.0.0.1"}},
+	}
+}
+
+func TestRedeployOptions(t *testing.T) {
+	opts, err := RedeployOptions(redeployWorkload())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Name != "test" || opts.Entrypoint.Name != "http" || opts.Podname != "muroq" || opts.Image != "python:3.10" || opts.Count != 1 {
+		t.Errorf("RedeployOptions() = %+v", opts)
+	}
+	if !reflect.DeepEqual(opts.Entrypoint.Commands, []string{"python3 -m http.server"}) || !reflect.DeepEqual(opts.Dns, []string{"8.8.8.8"}) {
+		t.Errorf("entrypoint label is not used, commands %v, dns %v", opts.Entrypoint.Commands, opts.Dns)
+	}
+	if !reflect.DeepEqual(opts.Entrypoint.Publish, []string{"8000"}) || opts.Entrypoint.Healthcheck == nil {
+		t.Errorf("ERU_META is not used, publish %v, healthcheck %v", opts.Entrypoint.Publish, opts.Entrypoint.Healthcheck)
+	}
+	if !reflect.DeepEqual(opts.Env, []string{"DEBUG=1"}) {
+		t.Errorf("env = %v", opts.Env)
+	}
+	if !reflect.DeepEqual(opts.Networks, map[string]string{"host": ""}) {
+		t.Errorf("networks = %v", opts.Networks)
+	}
+	if _, ok := opts.Labels["ERU"]; ok || opts.Labels["owner"] != "tonic" {
+		t.Errorf("labels = %v", opts.Labels)
+	}
+}
+
+func TestRedeployOptionsFails(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*corepb.Workload)
+		err    string
+	}{
+		{"invalid name", func(w *corepb.Workload) { w.Name = "test" }, ""},
+		{"no entrypoint label", func(w *corepb.Workload) { delete(w.Labels, types.LabelEntrypoint) }, "deployed by older cli"},
+		{"invalid entrypoint label", func(w *corepb.Workload) { w.Labels[types.LabelEntrypoint] = "{" }, "invalid label"},
+		{"invalid resources", func(w *corepb.Workload) { w.Resources = "{" }, "invalid resources"},
+		{"no status", func(w *corepb.Workload) { w.Status = nil }, "networks to join are unknown"},
+		{"no networks", func(w *corepb.Workload) { w.Status.Networks = nil }, "networks to join are unknown"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := redeployWorkload()
+			c.modify(w)
+			_, err := RedeployOptions(w)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("RedeployOptions() error = %v, want %q", err, c.err)
+			}
+		})
+	}
+}
+
+type copyClient struct {
+	corepb.CoreRPCClient
+	files  map[string]string
+	copied []string
+}
+
+type copyStream struct {
+	grpc.ClientStream
+	msgs []*corepb.CopyMessage
+}
+
+func (s *copyStream) Recv() (*corepb.CopyMessage, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return msg, nil
+}
+
+func (c *copyClient) Copy(_ context.Context, in *corepb.CopyOptions, _ ...grpc.CallOption) (corepb.CoreRPC_CopyClient, error) {
+	s := &copyStream{}
+	for id, paths := range in.Targets {
+		for _, path := range paths.Paths {
+			c.copied = append(c.copied, path)
+			content, ok := c.files[path]
+			if !ok {
+				s.msgs = append(s.msgs, &corepb.CopyMessage{Id: id, Path: path, Error: "not found"})
+				continue
+			}
+			buf := &bytes.Buffer{}
+			tw := tar.NewWriter(buf)
+			_ = tw.WriteHeader(&tar.Header{Name: filepath.Base(path), Typeflag: tar.TypeReg, Mode: 0600, Uid: 1000, Gid: 1001, Size: int64(len(content))})
+			_, _ = tw.Write([]byte(content))
+			_ = tw.Close()
+			// sent in chunks
+			b := buf.Bytes()
+			s.msgs = append(s.msgs,
+				&corepb.CopyMessage{Id: id, Path: path, Data: b[:len(b)/2]},
+				&corepb.CopyMessage{Id: id, Path: path, Data: b[len(b)/2:]},
+			)
+		}
+	}
+	return s, nil
+}
+
+func TestRedeployFiles(t *testing.T) {
+	client := &copyClient{files: map[string]string{"/etc/app.conf": "port = 8000", "/etc/key": "secret"}}
+	w := redeployWorkload()
+	data, modes, owners, err := RedeployFiles(context.Background(), client, w)
+	if err != nil || data != nil || len(client.copied) > 0 {
+		t.Fatalf("RedeployFiles() without files = %v, %v, copied %v", data, err, client.copied)
+	}
+
+	w.Labels[types.LabelEntrypoint] = `{"commands":["python3 -m http.server"],"files":["/etc/app.conf","/etc/key"]}`
+	data, modes, owners, err = RedeployFiles(context.Background(), client, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data["/etc/app.conf"]) != "port = 8000" || string(data["/etc/key"]) != "secret" {
+		t.Errorf("data = %q", data)
+	}
+	if modes["/etc/key"].Mode != 0600 || owners["/etc/key"].Uid != 1000 || owners["/etc/key"].Gid != 1001 {
+		t.Errorf("modes = %v, owners = %v", modes, owners)
+	}
+
+	w.Labels[types.LabelEntrypoint] = `{"commands":["python3 -m http.server"],"files":["/etc/app.conf","/etc/gone"]}`
+	if _, _, _, err := RedeployFiles(context.Background(), client, w); err == nil {
+		t.Error("RedeployFiles() should fail if a file can't be copied")
+	}
+}
@@ -0,0 +1,8 @@
+package utils
+
+import "strings"
+
+// ShellQuote quotes s as a single word for sh
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// GetSpecValues reads values files and --set pairs into one map,
+// later files override former ones, --set overrides all files.
+// --set a.b=c sets nested key, true and false are set as bools, others are kept as strings
+// so values like image tag 1.10 won't be turned into numbers
+func GetSpecValues(files []string, sets []string) (map[string]any, error) {
+	values := map[string]any{}
+	for _, file := range files {
+		data, err := GetSpecData(file)
+		if err != nil {
+			return nil, fmt.Errorf("[GetSpecValues] read values %s failed %v", file, err)
+		}
+		m := map[string]any{}
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("[GetSpecValues] parse values %s failed %v", file, err)
+		}
+		values = MergeMap(values, normalizeYAML(m).(map[string]any))
+	}
+
+	for _, set := range sets {
+		key, raw, ok := strings.Cut(set, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("[GetSpecValues] invalid --set %q, should be key=value", set)
+		}
+		var value any = raw
+		if b, err := strconv.ParseBool(raw); err == nil && (raw == "true" || raw == "false") {
+			value = b
+		}
+
+		keys := strings.Split(key, ".")
+		m := values
+		for _, k := range keys[:len(keys)-1] {
+			sub, ok := m[k].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				m[k] = sub
+			}
+			m = sub
+		}
+		m[keys[len(keys)-1]] = value
+	}
+	return values, nil
+}
+
+// RenderSpec renders spec as go template with values,
+// values are accessed like {{ .image.tag }}, besides builtin functions,
+// env, default and required are provided.
+// Missing keys fail the render, use index like {{ index . "port" | default 8000 }} for optional values
+func RenderSpec(name string, data []byte, values map[string]any) ([]byte, error) {
+	funcs := template.FuncMap{
+		"env": os.Getenv,
+		"default": func(d, v any) any {
+			if v == nil || v == "" {
+				return d
+			}
+			return v
+		},
+		"required": func(msg string, v any) (any, error) {
+			if v == nil || v == "" {
+				return nil, fmt.Errorf("%s", msg)
+			}
+			return v, nil
+		},
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("[RenderSpec] parse template %s failed %v", name, err)
+	}
+	out := bytes.Buffer{}
+	if err := tmpl.Execute(&out, values); err != nil {
+		return nil, fmt.Errorf("[RenderSpec] render %s failed %v", name, err)
+	}
+	return out.Bytes(), nil
+}
+
+// MergeMap deep merges overlay onto base,
+// maps are merged key by key, other values including lists are replaced
+func MergeMap(base, overlay map[string]any) map[string]any {
+	merged := map[string]any{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		bm, ok1 := merged[k].(map[string]any)
+		om, ok2 := v.(map[string]any)
+		if ok1 && ok2 {
+			merged[k] = MergeMap(bm, om)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// LoadSpec reads spec from uri, renders it with values if values files or sets are given,
+// then merges overlay specs onto it, overlays are rendered with the same values.
+// Without them the spec is returned as it is
+func LoadSpec(uri string, valueFiles, sets, overlays []string) ([]byte, error) {
+	data, err := GetSpecData(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	render := len(valueFiles) > 0 || len(sets) > 0
+	var values map[string]any
+	if render {
+		if values, err = GetSpecValues(valueFiles, sets); err != nil {
+			return nil, err
+		}
+		if data, err = RenderSpec(uri, data, values); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(overlays) == 0 {
+		return data, nil
+	}
+
+	// merge as MapSlice to keep the declared order, entrypoints are deployed in this order
+	merged := yaml.MapSlice{}
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		return nil, fmt.Errorf("[LoadSpec] parse spec %s failed %v", uri, err)
+	}
+	for _, overlay := range overlays {
+		od, err := GetSpecData(overlay)
+		if err != nil {
+			return nil, err
+		}
+		if render {
+			if od, err = RenderSpec(overlay, od, values); err != nil {
+				return nil, err
+			}
+		}
+		m := yaml.MapSlice{}
+		if err := yaml.Unmarshal(od, &m); err != nil {
+			return nil, fmt.Errorf("[LoadSpec] parse overlay %s failed %v", overlay, err)
+		}
+		merged = mergeMapSlice(merged, m)
+	}
+	return yaml.Marshal(merged)
+}
+
+// mergeMapSlice is MergeMap for yaml.MapSlice,
+// keys of base keep their order, new keys of overlay are appended
+func mergeMapSlice(base, overlay yaml.MapSlice) yaml.MapSlice {
+	merged := append(yaml.MapSlice{}, base...)
+	for _, item := range overlay {
+		found := false
+		for i := range merged {
+			if merged[i].Key != item.Key {
+				continue
+			}
+			found = true
+			bm, ok1 := merged[i].Value.(yaml.MapSlice)
+			om, ok2 := item.Value.(yaml.MapSlice)
+			if ok1 && ok2 {
+				merged[i].Value = mergeMapSlice(bm, om)
+			} else {
+				merged[i].Value = item.Value
+			}
+			break
+		}
+		if !found {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// normalizeYAML converts map[interface{}]interface{} decoded by yaml.v2
+// into map[string]any recursively, so they can be merged and templated
+func normalizeYAML(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := map[string]any{}
+		for k, v := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(v)
+		}
+		return m
+	case map[string]any:
+		for k, v := range t {
+			t[k] = normalizeYAML(v)
+		}
+		return t
+	case []any:
+		for i, v := range t {
+			t[i] = normalizeYAML(v)
+		}
+		return t
+	default:
+		return v
+	}
+}
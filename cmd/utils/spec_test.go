@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeMap(t *testing.T) {
+	base := map[string]any{
+		"image": map[string]any{"name": "python", "tag": "3.9"},
+		"ports": []any{80, 443},
+		"owner": "tonic",
+	}
+	overlay := map[string]any{
+		"image": map[string]any{"tag": "3.10"},
+		"ports": []any{8080},
+		"env":   "prod",
+	}
+	expected := map[string]any{
+		"image": map[string]any{"name": "python", "tag": "3.10"},
+		"ports": []any{8080},
+		"owner": "tonic",
+		"env":   "prod",
+	}
+	if merged := MergeMap(base, overlay); !reflect.DeepEqual(merged, expected) {
+		t.Errorf("MergeMap() = %v, want %v", merged, expected)
+	}
+	if tag := base["image"].(map[string]any)["tag"]; tag != "3.9" {
+		t.Errorf("base is changed, tag = %v", tag)
+	}
+}
+
+func TestGetSpecValues(t *testing.T) {
+	values, err := GetSpecValues(nil, []string{"image.tag=1.10", "debug=true", "owner=tonic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"image": map[string]any{"tag": "1.10"},
+		"debug": true,
+		"owner": "tonic",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("GetSpecValues() = %v, want %v", values, expected)
+	}
+
+	if _, err := GetSpecValues(nil, []string{"=1"}); err == nil {
+		t.Error("GetSpecValues() with empty key should fail")
+	}
+}
+
+func TestRenderSpec(t *testing.T) {
+	values := map[string]any{
+		"image": map[string]any{"tag": "3.10"},
+		"owner": "tonic",
+	}
+	cases := []struct {
+		name     string
+		spec     string
+		expected string
+		err      string
+	}{
+		{"value", "image: python:{{ .image.tag }}", "image: python:3.10", ""},
+		{"default of missing key", `port: {{ index . "port" | default 8000 }}`, "port: 8000", ""},
+		{"default of given key", `owner: {{ index . "owner" | default "nobody" }}`, "owner: tonic", ""},
+		{"required", `owner: {{ required "owner must be set" (index . "owner") }}`, "owner: tonic", ""},
+		{"required missing", `team: {{ required "team must be set" (index . "team") }}`, "", "team must be set"},
+		{"missing key", "port: {{ .port }}", "", "no entry for key"},
+		{"missing nested key", "image: python:{{ .image.version }}", "", "no entry for key"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := RenderSpec("test", []byte(c.spec), values)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("RenderSpec() error = %v, want %q", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != c.expected {
+				t.Errorf("RenderSpec() = %q, want %q", out, c.expected)
+			}
+		})
+	}
+}
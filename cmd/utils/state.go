@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// StateDir returns the directory to keep local states like replace journals,
+// it's $ERU_CLI_HOME/<name> or ~/.eru-cli/<name>, and will be created if not exists
+func StateDir(name string) (string, error) {
+	home := os.Getenv("ERU_CLI_HOME")
+	if home == "" {
+		userHome, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		home = filepath.Join(userHome, ".eru-cli")
+	}
+	dir := filepath.Join(home, name)
+	return dir, os.MkdirAll(dir, 0700)
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+)
+
+// WaitHealthy waits until all workloads are running and healthy, or any is removed, or timeout.
+// Health of each workload is returned in order of ids, error is only for rpc failures
+func WaitHealthy(ctx context.Context, client corepb.CoreRPCClient, ids []string, timeout time.Duration) ([]*types.WorkloadHealth, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	healths := make([]*types.WorkloadHealth, len(ids))
+	byID := map[string]*types.WorkloadHealth{}
+	for i, id := range ids {
+		healths[i] = &types.WorkloadHealth{ID: id}
+		byID[id] = healths[i]
+	}
+	done := func() bool {
+		for _, h := range healths {
+			if !h.OK() && !h.Deleted {
+				return false
+			}
+		}
+		return true
+	}
+	if len(ids) == 0 {
+		return healths, nil
+	}
+
+	// status stream can't filter by ids, filter by appname if they're of the same app
+	appname := ""
+	if resp, err := client.GetWorkloads(ctx, &corepb.WorkloadIDs{IDs: ids}); err == nil {
+		for i, w := range resp.Workloads {
+			name, _, _, err := coreutils.ParseWorkloadName(w.Name)
+			if err != nil || (i > 0 && name != appname) {
+				appname = ""
+				break
+			}
+			appname = name
+		}
+	}
+
+	// subscribe before getting current status, so no change is missed
+	stream, err := client.WorkloadStatusStream(ctx, &corepb.WorkloadStatusStreamOptions{Appname: appname})
+	if err != nil {
+		return healths, err
+	}
+
+	statuses, err := client.GetWorkloadsStatus(ctx, &corepb.WorkloadIDs{IDs: ids})
+	if err != nil {
+		return healths, err
+	}
+	for _, status := range statuses.Status {
+		if h, ok := byID[status.Id]; ok {
+			h.Reported, h.Running, h.Healthy = true, status.Running, status.Healthy
+		}
+	}
+
+	for !done() {
+		msg, err := stream.Recv()
+		if ctx.Err() != nil {
+			// timeout
+			return healths, nil
+		}
+		if err == io.EOF {
+			return healths, nil
+		}
+		if err != nil {
+			return healths, err
+		}
+		if msg == nil {
+			continue
+		}
+		h, ok := byID[msg.Id]
+		if !ok {
+			continue
+		}
+		switch {
+		case msg.Delete:
+			h.Deleted = true
+		case msg.Error != "":
+			h.Error = msg.Error
+		case msg.Status != nil:
+			h.Reported, h.Running, h.Healthy, h.Error = true, msg.Status.Running, msg.Status.Healthy, ""
+		}
+	}
+	return healths, nil
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = time.Minute
+)
+
+// Watcher keeps a stream watched until the context is done,
+// a broken stream is reconnected with exponential backoff
+type Watcher struct {
+	// Name is the tag in logs
+	Name string
+	// Connect opens the stream, and returns a function receiving and handling one message
+	Connect func(ctx context.Context) (recv func() error, err error)
+	// Resync is called after reconnecting, to catch up with what's missed during reconnecting
+	Resync func(ctx context.Context) error
+	// IdleTimeout reconnects if nothing is received for this long, to notice dead connections, 0 disables it,
+	// it can be changed by recv, and takes effect from the next message
+	IdleTimeout time.Duration
+}
+
+// Run watches until ctx is done, or an error not worth retrying is returned by eru-core
+func (w *Watcher) Run(ctx context.Context) error {
+	backoff := watchMinBackoff
+	for everConnected := false; ; {
+		connected, stable, err := w.watch(ctx, everConnected)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !retryable(err) {
+			return fmt.Errorf("[%s] %v", w.Name, err)
+		}
+		everConnected = everConnected || connected
+		// streams accepted and closed at once, like by a restarting eru-core, keep backing off
+		if stable {
+			backoff = watchMinBackoff
+		}
+
+		if err == io.EOF {
+			logrus.Warnf("[%s] Stream closed by eru-core, reconnect in %v", w.Name, backoff)
+		} else {
+			logrus.Warnf("[%s] Stream broken: %v, reconnect in %v", w.Name, err, backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > watchMaxBackoff {
+			backoff = watchMaxBackoff
+		}
+	}
+}
+
+// retryable tells if the error may be gone by reconnecting,
+// errors like wrong credentials or arguments are returned at once
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument, codes.Unimplemented:
+		return false
+	default:
+		return true
+	}
+}
+
+// watch connects and receives until the stream is broken,
+// connected tells if the stream is connected, quiet streams may receive nothing before broken,
+// stable tells if anything is received, or the stream is up for watchMaxBackoff at least
+func (w *Watcher) watch(ctx context.Context, reconnecting bool) (connected, stable bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	recv, err := w.Connect(ctx)
+	if err != nil {
+		return false, false, err
+	}
+	connectedAt := time.Now()
+	if reconnecting {
+		logrus.Infof("[%s] Reconnected, resume watching", w.Name)
+		if w.Resync != nil {
+			if err := w.Resync(ctx); err != nil {
+				logrus.Warnf("[%s] Failed to resync after reconnecting: %v", w.Name, err)
+			}
+		}
+	}
+
+	idle := make(chan struct{})
+	once := sync.Once{}
+	var timer *time.Timer
+	resetIdle := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		if w.IdleTimeout > 0 {
+			timer = time.AfterFunc(w.IdleTimeout, func() {
+				once.Do(func() {
+					close(idle)
+					cancel()
+				})
+			})
+		}
+	}
+	resetIdle()
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for received := false; ; received = true {
+		if err := recv(); err != nil {
+			stable := received || time.Since(connectedAt) >= watchMaxBackoff
+			select {
+			case <-idle:
+				return true, stable, fmt.Errorf("nothing received in %v", w.IdleTimeout)
+			default:
+				return true, stable, err
+			}
+		}
+		resetIdle()
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWatcherNotRetryable(t *testing.T) {
+	for _, code := range []codes.Code{codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument, codes.Unimplemented} {
+		connects := 0
+		w := &Watcher{
+			Name: "Test",
+			Connect: func(context.Context) (func() error, error) {
+				connects++
+				return nil, status.Error(code, "no")
+			},
+		}
+		if err := w.Run(context.Background()); err == nil || connects != 1 {
+			t.Errorf("Run() with %v = %v, connected %d times", code, err, connects)
+		}
+	}
+
+	// errors of receiving are checked too
+	w := &Watcher{
+		Name: "Test",
+		Connect: func(context.Context) (func() error, error) {
+			return func() error { return status.Error(codes.PermissionDenied, "no") }, nil
+		},
+	}
+	if err := w.Run(context.Background()); err == nil {
+		t.Error("Run() should return error of receiving")
+	}
+}
+
+// a stream closed at once is resynced after reconnecting, but keeps backing off
+func TestWatcherStreamClosedAtOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resyncs := 0
+	connectedAt := []time.Time{}
+	w := &Watcher{
+		Name: "Test",
+		Connect: func(context.Context) (func() error, error) {
+			connectedAt = append(connectedAt, time.Now())
+			if len(connectedAt) == 3 {
+				cancel()
+			}
+			return func() error { return status.Error(codes.Unavailable, "broken") }, nil
+		},
+		Resync: func(context.Context) error {
+			resyncs++
+			return nil
+		},
+	}
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(connectedAt) != 3 || resyncs != 2 {
+		t.Errorf("connected %d times, resynced %d times", len(connectedAt), resyncs)
+	}
+	if d := connectedAt[2].Sub(connectedAt[1]); d < 2*watchMinBackoff {
+		t.Errorf("reconnected after %v, backoff is reset", d)
+	}
+}
+
+// backoff is reset once anything is received
+func TestWatcherResetBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	connectedAt := []time.Time{}
+	w := &Watcher{
+		Name: "Test",
+		Connect: func(context.Context) (func() error, error) {
+			connectedAt = append(connectedAt, time.Now())
+			if len(connectedAt) == 3 {
+				cancel()
+			}
+			received := false
+			return func() error {
+				if !received {
+					received = true
+					return nil
+				}
+				return status.Error(codes.Unavailable, "broken")
+			}, nil
+		},
+	}
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := connectedAt[2].Sub(connectedAt[1]); d > watchMinBackoff+500*time.Millisecond {
+		t.Errorf("reconnected after %v, backoff is not reset", d)
+	}
+}
+
+func TestWatcherBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	connectedAt := []time.Time{}
+	w := &Watcher{
+		Name: "Test",
+		Connect: func(context.Context) (func() error, error) {
+			connectedAt = append(connectedAt, time.Now())
+			if len(connectedAt) == 3 {
+				cancel()
+			}
+			return nil, errors.New("unavailable")
+		},
+	}
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := connectedAt[2].Sub(connectedAt[1]); d < 2*watchMinBackoff {
+		t.Errorf("reconnected after %v, backoff is not doubled", d)
+	}
+}
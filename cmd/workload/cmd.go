@@ -1,6 +1,8 @@
 package workload
 
 import (
+	"time"
+
 	"github.com/projecteru2/cli/cmd/utils"
 	"github.com/projecteru2/core/strategy"
 
@@ -12,6 +14,7 @@ const (
 	specFileURI       = "<spec file uri>"
 	copyArgsUsage     = "workloadID:path1,path2,...,pathn"
 	sendArgsUsage     = "path1,path2,...pathn"
+	cpArgsUsage       = "workloadID:SRC_PATH DEST_PATH|-, or SRC_PATH|- workloadID:DEST_PATH"
 )
 
 // Command exports workload subommands
@@ -27,6 +30,38 @@ func Command() *cli.Command {
 				ArgsUsage: workloadArgsUsage,
 				Action:    utils.ExitCoder(cmdWorkloadGet),
 			},
+			{
+				Name:      "export-spec",
+				Usage:     "rebuild app spec from running workloads, and print the command to deploy it",
+				ArgsUsage: "appname|workloadID",
+				Action:    utils.ExitCoder(cmdWorkloadExportSpec),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "file",
+						Usage: "write the spec to this file instead of stdout",
+					},
+					&cli.BoolFlag{
+						Name:  "partial",
+						Usage: "export workloads without entrypoint label too, fields not recovered are listed after the spec",
+					},
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "diff two workloads, exit 1 if they differ",
+				ArgsUsage: "workloadID1 workloadID2",
+				Action:    utils.ExitCoder(cmdWorkloadDiff),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "files",
+						Usage: "also diff these files in workloads, e.g. /etc/hosts,/app/config.yaml",
+					},
+					&cli.BoolFlag{
+						Name:  "color",
+						Usage: "colorize the diff",
+					},
+				},
+			},
 			{
 				Name:      "logs",
 				Usage:     "get workload stream logs",
@@ -85,6 +120,20 @@ func Command() *cli.Command {
 						Name:  "extension",
 						Usage: "extension things",
 					},
+					&cli.IntFlag{
+						Name:  "interval",
+						Usage: "if given, will set status every INTERVAL seconds, and mark workloads unhealthy on exit",
+						Value: 0,
+					},
+					&cli.StringSliceFlag{
+						Name:  "probe",
+						Usage: "compute running and healthy by probes, can set multiple times, http://..., https://..., tcp:host:port or exec:command",
+					},
+					&cli.DurationFlag{
+						Name:  "probe-timeout",
+						Usage: "timeout of each probe",
+						Value: 3 * time.Second,
+					},
 				},
 				Action: utils.ExitCoder(cmdWorkloadSetStatus),
 			},
@@ -126,6 +175,10 @@ func Command() *cli.Command {
 						Name:  "statistics",
 						Usage: "Display the statistics of Workloads",
 					},
+					&cli.StringFlag{
+						Name:  "group-by",
+						Usage: "group statistics by node, pod, entry or label:<key>",
+					},
 				},
 			},
 			{
@@ -154,6 +207,15 @@ func Command() *cli.Command {
 						Aliases: []string{"f"},
 						Value:   false,
 					},
+					&cli.BoolFlag{
+						Name:  "wait",
+						Usage: "wait until workloads are running and healthy, exit with non-zero code if not",
+					},
+					&cli.DurationFlag{
+						Name:  "wait-timeout",
+						Usage: "how long to wait for workloads to be healthy",
+						Value: 5 * time.Minute,
+					},
 				},
 			},
 			{
@@ -202,8 +264,22 @@ func Command() *cli.Command {
 						Aliases: []string{"d"},
 						Value:   "/tmp",
 					},
+					&cli.BoolFlag{
+						Name:  "extract",
+						Usage: "extract files into <dir>/<shortid> instead of storing tarballs",
+					},
+					&cli.BoolFlag{
+						Name:  "overwrite",
+						Usage: "overwrite existing files",
+					},
 				},
 			},
+			{
+				Name:      "cp",
+				Usage:     "copy files or directories between a workload and local, like docker cp",
+				ArgsUsage: cpArgsUsage,
+				Action:    utils.ExitCoder(cmdWorkloadCp),
+			},
 			{
 				Name:      "send",
 				Usage:     "send file(s) to workload(s)",
@@ -218,13 +294,17 @@ func Command() *cli.Command {
 			},
 			{
 				Name:      "sendlarge",
-				Usage:     "send single large file to workload(s)",
+				Usage:     "send large file(s) to workload(s)",
 				ArgsUsage: sendArgsUsage,
 				Action:    utils.ExitCoder(cmdWorkloadSendLarge),
 				Flags: []cli.Flag{
 					&cli.StringSliceFlag{
 						Name:  "file",
-						Usage: "copy local file to workload, only can use single time. src_path:dst_path[:mode[:uid:gid]]",
+						Usage: "copy local file to workload, can use multiple times, files are sent one by one. src_path:dst_path[:mode[:uid:gid]]",
+					},
+					&cli.BoolFlag{
+						Name:  "no-verify",
+						Usage: "don't verify sha256 of files in workloads after sending",
 					},
 				},
 			},
@@ -242,10 +322,35 @@ func Command() *cli.Command {
 			},
 			{
 				Name:      "realloc",
-				Usage:     "realloc workloads resource",
+				Usage:     "realloc workloads resource, of given workloads or workloads selected by --app/--entry/--node/--label",
 				ArgsUsage: workloadArgsUsage,
 				Action:    utils.ExitCoder(cmdWorkloadRealloc),
 				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "app",
+						Usage: "realloc all workloads of this app",
+					},
+					&cli.StringFlag{
+						Name:  "entry",
+						Usage: "realloc workloads of this entry",
+					},
+					&cli.StringSliceFlag{
+						Name:  "node",
+						Usage: "realloc workloads on these nodes, can set multiple times",
+					},
+					&cli.StringSliceFlag{
+						Name:  "label",
+						Usage: "realloc workloads with these labels, can set multiple times, like key=value",
+					},
+					&cli.IntFlag{
+						Name:  "concurrency",
+						Usage: "how many workloads to realloc at the same time",
+						Value: 5,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "show resources after realloc and check capacity of nodes, without reallocating",
+					},
 					&cli.Float64Flag{
 						Name:  "cpu-request",
 						Usage: "cpu request increment/decrement",
@@ -310,13 +415,14 @@ func Command() *cli.Command {
 			},
 			{
 				Name:      "exec",
-				Usage:     "run a command in a running workload",
-				ArgsUsage: "workloadID -- cmd1 cmd2 cmd3",
+				Usage:     "run a command in a running workload, or in all workloads selected by --app/--entry/--node/--label",
+				ArgsUsage: "workloadID -- cmd1 cmd2 cmd3, or --app appname -- cmd1 cmd2 cmd3",
 				Action:    utils.ExitCoder(cmdWorkloadExec),
 				Flags: []cli.Flag{
 					&cli.BoolFlag{
 						Name:    "interactive",
 						Aliases: []string{"i"},
+						Usage:   "forward stdin, piped stdin is not binary safe and is refused if it starts with byte 0x80 or 0x1d",
 						Value:   false,
 					},
 					&cli.StringSliceFlag{
@@ -330,6 +436,50 @@ func Command() *cli.Command {
 						Usage:   "/path/to/workdir",
 						Value:   "/",
 					},
+					&cli.StringFlag{
+						Name:  "app",
+						Usage: "execute in all workloads of this app",
+					},
+					&cli.StringFlag{
+						Name:  "entry",
+						Usage: "execute in workloads of this entry",
+					},
+					&cli.StringSliceFlag{
+						Name:  "node",
+						Usage: "execute in workloads on these nodes, can set multiple times",
+					},
+					&cli.StringSliceFlag{
+						Name:  "label",
+						Usage: "execute in workloads with these labels, can set multiple times, like key=value",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Usage: "how many workloads to execute at the same time",
+						Value: 10,
+					},
+					&cli.StringFlag{
+						Name:    "record-session",
+						Usage:   "record the interactive session in asciicast v2 format to this file, or to a new file in this directory",
+						EnvVars: []string{"ERU_RECORD_SESSION"},
+					},
+					&cli.BoolFlag{
+						Name:    "require-session-record",
+						Usage:   "refuse interactive sessions without --record-session",
+						EnvVars: []string{"ERU_REQUIRE_SESSION_RECORD"},
+					},
+				},
+			},
+			{
+				Name:      "port-forward",
+				Usage:     "forward local ports to ports in a workload through exec",
+				ArgsUsage: "workloadID LOCAL_PORT:WORKLOAD_PORT [LOCAL_PORT:WORKLOAD_PORT...]",
+				Action:    utils.ExitCoder(cmdWorkloadPortForward),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "address",
+						Usage: "local address to listen",
+						Value: "127.0.0.1",
+					},
 				},
 			},
 			{
@@ -403,8 +553,79 @@ func Command() *cli.Command {
 						Name:  "after-create",
 						Usage: "run commands after create",
 					},
+					&cli.Float64Flag{
+						Name:  "cpu-request",
+						Usage: "how many cpu to request, inherit from old workload if not set",
+					},
+					&cli.Float64Flag{
+						Name:  "cpu-limit",
+						Usage: "how many cpu to limit, inherit from old workload if not set",
+					},
+					&cli.Float64Flag{
+						Name:  "cpu",
+						Usage: "shortcut for cpu-request/limit, set them equally to this value",
+					},
+					&cli.StringFlag{
+						Name:  "memory-request",
+						Usage: "how many memory to request like 1M or 1G, support K, M, G, T, inherit from old workload if not set",
+					},
+					&cli.StringFlag{
+						Name:  "memory-limit",
+						Usage: "how many memory to limit like 1M or 1G, support K, M, G, T, inherit from old workload if not set",
+					},
+					&cli.StringFlag{
+						Name:  "memory",
+						Usage: "shortcut for memory-request/limit, set them equally to this value",
+					},
+					&cli.StringFlag{
+						Name:  "storage-request",
+						Usage: "how many storage to request quota like 1M or 1G, support K, M, G, T, inherit from old workload if not set",
+					},
+					&cli.StringFlag{
+						Name:  "storage-limit",
+						Usage: "how many storage to limit quota like 1M or 1G, support K, M, G, T, inherit from old workload if not set",
+					},
+					&cli.StringFlag{
+						Name:  "storage",
+						Usage: "shortcut for storage-request/limit, set them equally to this value",
+					},
+					&cli.StringFlag{
+						Name:  "extra-resource-increments",
+						Usage: "extra resource increments in json, added to resources of old workloads, same as realloc",
+					},
+					&cli.BoolFlag{
+						Name:  "wait",
+						Usage: "wait until workloads are running and healthy, exit with non-zero code if not",
+					},
+					&cli.DurationFlag{
+						Name:  "wait-timeout",
+						Usage: "how long to wait for workloads to be healthy",
+						Value: 5 * time.Minute,
+					},
+					&cli.StringFlag{
+						Name:  "report",
+						Usage: "write the report to file, in yaml if file ends with .yaml or .yml, otherwise in json",
+					},
+					&cli.StringSliceFlag{
+						Name:  "values",
+						Usage: "values file to render specs as go template, can use multiple times, later ones override former ones",
+					},
+					&cli.StringSliceFlag{
+						Name:  "set",
+						Usage: "set template value, can use multiple times, overrides values files, e.g., image.tag=v1",
+					},
+					&cli.StringSliceFlag{
+						Name:  "overlay",
+						Usage: "overlay specs deep merged onto the specs, can use multiple times, e.g., prod.yaml",
+					},
 				},
 			},
+			{
+				Name:      "rollback",
+				Usage:     "roll back a replace recorded in journal, list journals if no journal id given",
+				ArgsUsage: "[<journal id>]",
+				Action:    utils.ExitCoder(cmdWorkloadRollback),
+			},
 			{
 				Name:      "deploy",
 				Usage:     "deploy workloads by params",
@@ -415,13 +636,25 @@ func Command() *cli.Command {
 						Name:  "dry-run",
 						Usage: "dry run show capacity",
 					},
+					&cli.BoolFlag{
+						Name:  "plan",
+						Usage: "show resolved deploy options and what would change on existing workloads, without deploying",
+					},
 					&cli.StringFlag{
 						Name:  "pod",
 						Usage: "where to run",
 					},
-					&cli.StringFlag{
+					&cli.StringSliceFlag{
 						Name:  "entry",
-						Usage: "which entry",
+						Usage: "which entry, can use multiple times, entries are deployed in declared order",
+					},
+					&cli.BoolFlag{
+						Name:  "all-entries",
+						Usage: "deploy all entries of the specs in declared order",
+					},
+					&cli.BoolFlag{
+						Name:  "parallel",
+						Usage: "deploy entries in parallel instead of declared order",
 					},
 					&cli.StringFlag{
 						Name:  "image",
@@ -545,6 +778,31 @@ func Command() *cli.Command {
 						Usage: "add extra resource requests",
 						Value: "",
 					},
+					&cli.BoolFlag{
+						Name:  "wait",
+						Usage: "wait until workloads are running and healthy, exit with non-zero code if not",
+					},
+					&cli.DurationFlag{
+						Name:  "wait-timeout",
+						Usage: "how long to wait for workloads to be healthy",
+						Value: 5 * time.Minute,
+					},
+					&cli.StringFlag{
+						Name:  "report",
+						Usage: "write the report to file, in yaml if file ends with .yaml or .yml, otherwise in json",
+					},
+					&cli.StringSliceFlag{
+						Name:  "values",
+						Usage: "values file to render specs as go template, can use multiple times, later ones override former ones",
+					},
+					&cli.StringSliceFlag{
+						Name:  "set",
+						Usage: "set template value, can use multiple times, overrides values files, e.g., image.tag=v1",
+					},
+					&cli.StringSliceFlag{
+						Name:  "overlay",
+						Usage: "overlay specs deep merged onto the specs, can use multiple times, e.g., prod.yaml",
+					},
 				},
 			},
 		},
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -15,10 +16,12 @@ import (
 )
 
 type controlWorkloadsOptions struct {
-	client corepb.CoreRPCClient
-	ids    []string
-	action string
-	force  bool
+	client      corepb.CoreRPCClient
+	ids         []string
+	action      string
+	force       bool
+	wait        bool
+	waitTimeout time.Duration
 }
 
 func (o *controlWorkloadsOptions) run(ctx context.Context) error {
@@ -48,7 +51,10 @@ func (o *controlWorkloadsOptions) run(ctx context.Context) error {
 			logrus.Errorf("[ControlWorkload] Failed %s", msg.Error)
 		}
 	}
-	return nil
+	if !o.wait {
+		return nil
+	}
+	return waitHealthy(ctx, o.client, o.ids, o.waitTimeout)
 }
 
 func createControlWorkloadsOptions(c *cli.Context, action string) (*controlWorkloadsOptions, error) {
@@ -75,6 +81,7 @@ func cmdWorkloadStart(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	o.wait, o.waitTimeout = c.Bool("wait"), c.Duration("wait-timeout")
 	return o.run(c.Context)
 }
 
@@ -6,10 +6,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
 	corepb "github.com/projecteru2/core/rpc/gen"
 	coreutils "github.com/projecteru2/core/utils"
 
@@ -23,12 +26,29 @@ type copyWorkloadsOptions struct {
 	dir string
 	// map workloadID -> list of path of files
 	sources map[string][]string
+	// extract tarballs into <dir>/<shortid>
+	extract bool
+	// overwrite existing files instead of failing
+	overwrite bool
+}
+
+// copyWriter streams data of a copied path to disk,
+// either into a tarball or through a pipe into an extractor
+type copyWriter struct {
+	id     string
+	source string
+	w      io.WriteCloser
+	done   chan error
+	files  []*types.CopiedFile
+	err    error
 }
 
 func (o *copyWorkloadsOptions) run(ctx context.Context) error {
 	targets := map[string]*corepb.CopyPaths{}
+	manifests := map[string]*types.CopyManifest{}
 	for id, paths := range o.sources {
 		targets[id] = &corepb.CopyPaths{Paths: paths}
+		manifests[id] = &types.CopyManifest{ID: id, Files: []*types.CopiedFile{}}
 	}
 
 	resp, err := o.client.Copy(ctx, &corepb.CopyOptions{Targets: targets})
@@ -41,43 +61,150 @@ func (o *copyWorkloadsOptions) run(ctx context.Context) error {
 		return err
 	}
 
-	files := make(map[string][]byte)
+	// chunks of different workloads come interleaved, so keep a writer for each path of each workload
+	writers := map[string]*copyWriter{}
+	closeWriter := func(key string) {
+		cw, ok := writers[key]
+		if !ok {
+			return
+		}
+		delete(writers, key)
+		m := manifests[cw.id]
+		if err := cw.close(); err != nil {
+			m.Errors = append(m.Errors, fmt.Sprintf("%s: %v", cw.source, err))
+		}
+		m.Files = append(m.Files, cw.files...)
+	}
 
+	var streamErr error
 	for {
 		msg, err := resp.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return err
+			streamErr = err
+			break
+		}
+		if _, ok := manifests[msg.Id]; !ok {
+			manifests[msg.Id] = &types.CopyManifest{ID: msg.Id, Files: []*types.CopiedFile{}}
 		}
 
+		key := msg.Id + ":" + msg.Path
 		if msg.Error != "" {
-			logrus.Errorf("[Copy] Failed %s %s", coreutils.ShortID(msg.Id), msg.Error)
+			logrus.Errorf("[Copy] Failed %s %s %s", coreutils.ShortID(msg.Id), msg.Path, msg.Error)
+			closeWriter(key)
+			manifests[msg.Id].Errors = append(manifests[msg.Id].Errors, fmt.Sprintf("%s: %s", msg.Path, msg.Error))
 			continue
 		}
 
-		filename := fmt.Sprintf("%s-%s-%s.tar", coreutils.ShortID(msg.Id), msg.Name, now)
-		files[filename] = append(files[filename], msg.Data...)
-	}
-
-	for filename, content := range files {
-		storePath := filepath.Join(o.dir, filename)
-		if _, err := os.Stat(storePath); err != nil {
-			f, err := os.Create(storePath)
-			if err != nil {
-				logrus.Errorf("[Copy] Error during create backup file %s: %v", storePath, err)
-				continue
-			}
-			if _, err := f.Write(content); err != nil {
-				logrus.Errorf("[Copy] Write file error %v", err)
+		cw, ok := writers[key]
+		if !ok {
+			if cw, err = o.newWriter(msg.Id, msg.Path, now); err != nil {
+				logrus.Errorf("[Copy] Failed %s %s %v", coreutils.ShortID(msg.Id), msg.Path, err)
+				cw = &copyWriter{id: msg.Id, source: msg.Path, err: err}
 			}
-			f.Close()
+			writers[key] = cw
 		}
+		cw.write(msg.Data)
+	}
+
+	for key := range writers {
+		closeWriter(key)
+	}
+
+	ids := []string{}
+	failed := 0
+	for id, m := range manifests {
+		ids = append(ids, id)
+		failed += len(m.Errors)
+		sort.SliceStable(m.Files, func(i, j int) bool { return m.Files[i].Source < m.Files[j].Source })
+	}
+	sort.Strings(ids)
+	result := []*types.CopyManifest{}
+	for _, id := range ids {
+		result = append(result, manifests[id])
+	}
+	describe.CopyManifests(result...)
+
+	if streamErr != nil {
+		return streamErr
+	}
+	if failed > 0 {
+		return fmt.Errorf("[Copy] %d error(s) during copying", failed)
 	}
 	return nil
 }
 
+// newWriter opens a writer for path of workload,
+// the tarball is <dir>/<shortid>-<name>-<now>.tar, or extracted into <dir>/<shortid>
+func (o *copyWorkloadsOptions) newWriter(id, path, now string) (*copyWriter, error) {
+	cw := &copyWriter{id: id, source: path}
+	if !o.extract {
+		storePath := filepath.Join(o.dir, fmt.Sprintf("%s-%s-%s.tar", coreutils.ShortID(id), filepath.Base(path), now))
+		flags := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+		if o.overwrite {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		}
+		f, err := os.OpenFile(storePath, flags, 0600)
+		if err != nil {
+			return nil, err
+		}
+		cw.w = f
+		cw.files = []*types.CopiedFile{{Source: path, Path: storePath}}
+		return cw, nil
+	}
+
+	root := filepath.Join(o.dir, coreutils.ShortID(id))
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	r, w := io.Pipe()
+	cw.w = w
+	cw.done = make(chan error, 1)
+	go func() {
+		files, err := extractTar(r, root, o.overwrite)
+		for _, f := range files {
+			f.Source = path
+		}
+		cw.files = files
+		// unblock the writer if extracting stopped early
+		r.CloseWithError(err) //nolint
+		cw.done <- err
+	}()
+	return cw, nil
+}
+
+func (cw *copyWriter) write(data []byte) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.Write(data)
+	if err != nil {
+		cw.err = err
+		return
+	}
+	if cw.done == nil {
+		cw.files[0].Size += int64(n)
+	}
+}
+
+func (cw *copyWriter) close() error {
+	if cw.w == nil {
+		return cw.err
+	}
+	err := cw.w.Close()
+	if cw.done != nil {
+		if extractErr := <-cw.done; extractErr != nil {
+			return extractErr
+		}
+	}
+	if cw.err != nil {
+		return cw.err
+	}
+	return err
+}
+
 func cmdWorkloadCopy(c *cli.Context) error {
 	client, err := utils.NewCoreRPCClient(c)
 	if err != nil {
@@ -104,9 +231,11 @@ func cmdWorkloadCopy(c *cli.Context) error {
 	}
 
 	o := &copyWorkloadsOptions{
-		client:  client,
-		sources: sources,
-		dir:     c.String("dir"),
+		client:    client,
+		sources:   sources,
+		dir:       c.String("dir"),
+		extract:   c.Bool("extract"),
+		overwrite: c.Bool("overwrite"),
 	}
 	return o.run(c.Context)
 }
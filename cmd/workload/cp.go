@@ -0,0 +1,198 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+type cpWorkloadOptions struct {
+	client corepb.CoreRPCClient
+	// workload on either side
+	id string
+	// path in workload
+	remote string
+	// local path, - means stdout or stdin
+	local string
+	// pull from workload, or push to workload
+	pull bool
+}
+
+func (o *cpWorkloadOptions) run(ctx context.Context) error {
+	if o.pull {
+		return o.pullFromWorkload(ctx)
+	}
+	return o.pushToWorkload(ctx)
+}
+
+// pullFromWorkload packs remote path by tar in workload, the tarball is sent as base64
+// since output of exec is split by lines, then it's unpacked to local path
+func (o *cpWorkloadOptions) pullFromWorkload(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	remote := path.Clean(o.remote)
+	// exit with the code of tar instead of base64
+	script := fmt.Sprintf(
+		"exec 3>&1; code=$({ { tar -C %s -cf - %s; echo $? >&4; } | base64 >&3; } 4>&1); exit $code",
+		utils.ShellQuote(path.Dir(remote)), utils.ShellQuote(path.Base(remote)),
+	)
+	r, w := io.Pipe()
+	defer r.Close()
+	go func() {
+		stderr := &bytes.Buffer{}
+		code, err := runInWorkload(ctx, o.client, &corepb.ExecuteWorkloadOptions{WorkloadId: o.id, Commands: []string{"sh", "-c", script}}, w, stderr)
+		if err == nil && code != 0 {
+			err = fmt.Errorf("tar %s exited with %d, %s", remote, code, strings.TrimSpace(stderr.String()))
+		}
+		w.CloseWithError(err) //nolint
+	}()
+	tarball := base64.NewDecoder(base64.StdEncoding, r)
+
+	if o.local == "-" {
+		_, err := io.Copy(os.Stdout, tarball)
+		return err
+	}
+
+	// copy into local if it's a directory, otherwise copy as local
+	if fi, err := os.Stat(o.local); err == nil && fi.IsDir() {
+		if _, err := extractTar(tarball, o.local, true); err != nil {
+			return fmt.Errorf("[Cp] extract %s failed %v", remote, err)
+		}
+		logrus.Infof("[Cp] Copied %s:%s to %s", coreutils.ShortID(o.id), remote, filepath.Join(o.local, path.Base(remote)))
+		return nil
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(o.local), ".eru-cli-cp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if _, err := extractTar(tarball, tmp, true); err != nil {
+		return fmt.Errorf("[Cp] extract %s failed %v", remote, err)
+	}
+	if err := os.RemoveAll(o.local); err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(tmp, path.Base(remote)), o.local); err != nil {
+		return err
+	}
+	logrus.Infof("[Cp] Copied %s:%s to %s", coreutils.ShortID(o.id), remote, o.local)
+	return nil
+}
+
+// pushToWorkload packs local path into a temporary tarball, sends it to workload,
+// then unpacks it in workload by tar
+func (o *cpWorkloadOptions) pushToWorkload(ctx context.Context) error {
+	f, err := os.CreateTemp("", "eru-cli-cp-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	base := ""
+	if o.local == "-" {
+		// stdin is a tarball already
+		if _, err := io.Copy(f, os.Stdin); err != nil {
+			return err
+		}
+	} else {
+		base = filepath.Base(filepath.Clean(o.local))
+		if err := writeTar(f, o.local); err != nil {
+			return fmt.Errorf("[Cp] pack %s failed %v", o.local, err)
+		}
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	tarball := fmt.Sprintf("/tmp/.eru-cli-cp-%d.tar", time.Now().UnixNano())
+	if err := streamLargeFile(ctx, o.client, []string{o.id}, tarball, f, size, &corepb.FileMode{Mode: 0600}, &corepb.FileOwner{}); err != nil {
+		return fmt.Errorf("[Cp] send %s failed %v", o.local, err)
+	}
+
+	// copy into remote if it's a directory, otherwise copy as remote like docker cp
+	remote := path.Clean(o.remote)
+	script := fmt.Sprintf("trap 'rm -f %[1]s' EXIT; set -e; tar -xpf %[1]s -C %[2]s", utils.ShellQuote(tarball), utils.ShellQuote(remote))
+	if base != "" {
+		script = fmt.Sprintf(`trap 'rm -f %[1]s' EXIT
+if [ -d %[2]s ]; then tar -xpf %[1]s -C %[2]s; exit; fi
+tmp=$(mktemp -d %[3]s) || exit 1
+tar -xpf %[1]s -C "$tmp" && rm -rf %[2]s && mv "$tmp"/%[4]s %[2]s; code=$?
+rm -rf "$tmp"; exit $code`,
+			utils.ShellQuote(tarball), utils.ShellQuote(remote),
+			utils.ShellQuote(path.Join(path.Dir(remote), ".eru-cli-cp-XXXXXX")), utils.ShellQuote(base),
+		)
+	}
+	stderr := &bytes.Buffer{}
+	code, err := runInWorkload(ctx, o.client, &corepb.ExecuteWorkloadOptions{WorkloadId: o.id, Commands: []string{"sh", "-c", script}}, io.Discard, stderr)
+	if err != nil {
+		return fmt.Errorf("[Cp] unpack in workload failed %v", err)
+	}
+	if code != 0 {
+		return fmt.Errorf("[Cp] unpack in workload exited with %d, %s", code, strings.TrimSpace(stderr.String()))
+	}
+	logrus.Infof("[Cp] Copied %s to %s:%s", o.local, coreutils.ShortID(o.id), remote)
+	return nil
+}
+
+// splitCpPath splits workloadID:/path, or returns false for local paths
+func splitCpPath(arg string) (string, string, bool) {
+	if filepath.IsAbs(arg) || strings.HasPrefix(arg, ".") {
+		return "", "", false
+	}
+	id, p, ok := strings.Cut(arg, ":")
+	if !ok || id == "" || p == "" {
+		return "", "", false
+	}
+	return id, p, true
+}
+
+func cmdWorkloadCp(c *cli.Context) error {
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 2 {
+		return fmt.Errorf("[Cp] SRC and DST should be given")
+	}
+	src, dst := c.Args().Get(0), c.Args().Get(1)
+	srcID, srcPath, srcRemote := splitCpPath(src)
+	dstID, dstPath, dstRemote := splitCpPath(dst)
+
+	o := &cpWorkloadOptions{client: client}
+	switch {
+	case srcRemote && dstRemote:
+		return fmt.Errorf("[Cp] copying between workloads is not supported")
+	case srcRemote:
+		o.id, o.remote, o.local, o.pull = srcID, srcPath, dst, true
+	case dstRemote:
+		o.id, o.remote, o.local = dstID, dstPath, src
+	default:
+		return fmt.Errorf("[Cp] one of SRC and DST should be workloadID:path")
+	}
+	if !path.IsAbs(o.remote) {
+		return fmt.Errorf("[Cp] path in workload should be absolute")
+	}
+	return o.run(c.Context)
+}
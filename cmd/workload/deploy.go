@@ -5,8 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -20,49 +21,189 @@ import (
 
 type deployWorkloadsOptions struct {
 	client      corepb.CoreRPCClient
-	opts        *corepb.DeployOptions
+	opts        []*corepb.DeployOptions
 	dryRun      bool
+	plan        bool
 	autoReplace bool
+	parallel    bool
+	wait        bool
+	waitTimeout time.Duration
+	reportFile  string
 }
 
 func (o *deployWorkloadsOptions) run(ctx context.Context) error {
+	if o.plan {
+		for _, opts := range o.opts {
+			if err := showPlan(ctx, o.client, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if o.dryRun {
-		r, err := o.client.CalculateCapacity(ctx, o.opts)
+		for _, opts := range o.opts {
+			r, err := o.client.CalculateCapacity(ctx, opts)
+			if err != nil {
+				return fmt.Errorf("[Deploy] Calculate capacity of %s failed %v", opts.Entrypoint.Name, err)
+			}
+			logrus.Infof("[Deploy] Entry %s capacity total %v", opts.Entrypoint.Name, r.Total)
+			for nodename, capacity := range r.NodeCapacities {
+				logrus.Infof("[Deploy] Entry %s node %v capacity %v", opts.Entrypoint.Name, nodename, capacity)
+			}
+		}
+		return nil
+	}
+
+	// keep the output of deploying a single entry as it was
+	if len(o.opts) == 1 {
+		msgs, err := o.deploy(ctx, o.opts[0])
+		report := &types.DeployReport{Workloads: workloadReports(o.opts[0].Entrypoint.Name, msgs)}
+		if err := showReport(report, o.reportFile); err != nil {
+			return err
+		}
 		if err != nil {
-			return fmt.Errorf("[Deploy] Calculate capacity failed %v", err)
+			return err
 		}
-		logrus.Infof("[Deploy] Capacity total %v", r.Total)
-		for nodename, capacity := range r.NodeCapacities {
-			logrus.Infof("[Deploy] Node %v capacity %v", nodename, capacity)
+		if o.wait {
+			if err := waitHealthy(ctx, o.client, deployedIDs(msgs), o.waitTimeout); err != nil {
+				return err
+			}
 		}
-		return nil
+		return reportFailure("Deploy", report)
+	}
+
+	results := make([]*types.EntrypointResult, len(o.opts))
+	workloads := make([][]*types.WorkloadReport, len(o.opts))
+	if o.parallel {
+		wg := sync.WaitGroup{}
+		for i, opts := range o.opts {
+			wg.Add(1)
+			go func(i int, opts *corepb.DeployOptions) {
+				defer wg.Done()
+				results[i], workloads[i] = o.deployEntry(ctx, opts)
+			}(i, opts)
+		}
+		wg.Wait()
+	} else {
+		failed := false
+		for i, opts := range o.opts {
+			if failed {
+				results[i] = &types.EntrypointResult{Entrypoint: opts.Entrypoint.Name, Skipped: true}
+				continue
+			}
+			results[i], workloads[i] = o.deployEntry(ctx, opts)
+			// later entries may depend on former ones
+			failed = results[i].Error != "" || results[i].Failed > 0
+		}
+	}
+
+	report := &types.DeployReport{Entrypoints: results, Workloads: []*types.WorkloadReport{}}
+	for _, w := range workloads {
+		report.Workloads = append(report.Workloads, w...)
+	}
+	if err := showReport(report, o.reportFile); err != nil {
+		return err
+	}
+	if err := reportFailure("Deploy", report); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Skipped || r.Error != "" {
+			return fmt.Errorf("[Deploy] not all entries are deployed")
+		}
+	}
+	return nil
+}
+
+// deployEntry deploys an entry and collects the result and reports of workloads
+func (o *deployWorkloadsOptions) deployEntry(ctx context.Context, opts *corepb.DeployOptions) (*types.EntrypointResult, []*types.WorkloadReport) {
+	result := &types.EntrypointResult{Entrypoint: opts.Entrypoint.Name, Action: "create"}
+	msgs, err := o.deploy(ctx, opts)
+	if err != nil {
+		result.Error = err.Error()
 	}
+	result.IDs = deployedIDs(msgs)
+	reports := workloadReports(opts.Entrypoint.Name, msgs)
+	for _, r := range reports {
+		result.Action = r.Action
+		if !r.Success {
+			result.Failed++
+			continue
+		}
+		result.Succeeded++
+	}
+
+	if o.wait && result.Error == "" && len(result.IDs) > 0 {
+		if err := waitHealthy(ctx, o.client, result.IDs, o.waitTimeout); err != nil {
+			result.Error = err.Error()
+		}
+	}
+	return result, reports
+}
+
+// deployedIDs returns IDs of workloads created successfully
+func deployedIDs(msgs []any) []string {
+	ids := []string{}
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case *corepb.CreateWorkloadMessage:
+			if m.Success {
+				ids = append(ids, m.Id)
+			}
+		case *corepb.ReplaceWorkloadMessage:
+			if m.Error == "" && m.Create != nil && m.Create.Success {
+				ids = append(ids, m.Create.Id)
+			}
+		}
+	}
+	return ids
+}
 
+// deploy creates workloads, or replaces them if auto replace is set and there are workloads to replace,
+// messages are returned as they are
+func (o *deployWorkloadsOptions) deploy(ctx context.Context, opts *corepb.DeployOptions) ([]any, error) {
+	msgs := []any{}
 	if !o.autoReplace {
-		return doCreateWorkload(ctx, o.client, o.opts)
+		createMsgs, err := doCreateWorkload(ctx, o.client, opts)
+		for _, msg := range createMsgs {
+			msgs = append(msgs, msg)
+		}
+		return msgs, err
 	}
 
 	lsOpts := &corepb.ListWorkloadsOptions{
-		Appname:    o.opts.Name,
-		Entrypoint: o.opts.Entrypoint.Name,
+		Appname:    opts.Name,
+		Entrypoint: opts.Entrypoint.Name,
 		Labels:     nil,
 		Limit:      1, // 至少有一个可以被替换的
 	}
 	resp, err := o.client.ListWorkloads(ctx, lsOpts)
 	if err != nil {
-		return fmt.Errorf("[Deploy] check workload failed %v", err)
+		return nil, fmt.Errorf("[Deploy] check workload failed %v", err)
 	}
 	_, err = resp.Recv()
 	if err == io.EOF {
-		logrus.Warn("[Deploy] there is no Workloads for replace")
-		return doCreateWorkload(ctx, o.client, o.opts)
+		logrus.Warnf("[Deploy] there is no Workloads of %s for replace", opts.Entrypoint.Name)
+		createMsgs, err := doCreateWorkload(ctx, o.client, opts)
+		for _, msg := range createMsgs {
+			msgs = append(msgs, msg)
+		}
+		return msgs, err
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// 强制继承网络
-	networkInherit := len(o.opts.Networks) == 0
-	return doReplaceWorkload(ctx, o.client, o.opts, networkInherit, nil, nil)
+	networkInherit := len(opts.Networks) == 0
+	replaceMsgs, err := doReplaceWorkload(ctx, o.client, &corepb.ReplaceOptions{
+		DeployOpt:      opts,
+		Networkinherit: networkInherit,
+	}, "")
+	for _, msg := range replaceMsgs {
+		msgs = append(msgs, msg)
+	}
+	return msgs, err
 }
 
 func cmdWorkloadDeploy(c *cli.Context) error {
@@ -71,42 +212,112 @@ func cmdWorkloadDeploy(c *cli.Context) error {
 		return err
 	}
 
-	for _, key := range []string{"pod", "entry", "image"} {
+	for _, key := range []string{"pod", "image"} {
 		if c.String(key) == "" {
 			return fmt.Errorf("[Deploy] no %s given", key)
 		}
 	}
-	if strings.Contains(c.String("entry"), "_") {
-		return fmt.Errorf("[Deploy] entry can not contain _")
+	entries := c.StringSlice("entry")
+	if len(entries) == 0 && !c.Bool("all-entries") {
+		return fmt.Errorf("[Deploy] no entry given")
 	}
 
-	opts, err := generateDeployOptions(c)
+	specs, err := loadSpecs(c)
 	if err != nil {
 		return err
 	}
+	if entries, err = selectEntries(specs, entries, c.Bool("all-entries")); err != nil {
+		return err
+	}
+	// entries from specs are checked too, core can't parse workload names with _ in entry
+	for _, entry := range entries {
+		if strings.Contains(entry, "_") {
+			return fmt.Errorf("[Deploy] entry %s can not contain _", entry)
+		}
+	}
 
 	o := &deployWorkloadsOptions{
 		client:      client,
-		opts:        opts,
 		dryRun:      c.Bool("dry-run"),
+		plan:        c.Bool("plan"),
 		autoReplace: c.Bool("auto-replace"),
+		parallel:    c.Bool("parallel"),
+		wait:        c.Bool("wait"),
+		waitTimeout: c.Duration("wait-timeout"),
+		reportFile:  c.String("report"),
+	}
+	revision, err := newRevision(specs, c.String("image"))
+	if err != nil {
+		return fmt.Errorf("[Deploy] generate revision failed %v", err)
+	}
+	for _, entry := range entries {
+		opts, err := generateDeployOptions(c, specs, entry)
+		if err != nil {
+			return err
+		}
+		stampRevision(opts, revision)
+		o.opts = append(o.opts, opts)
 	}
 	return o.run(c.Context)
 }
 
-func doCreateWorkload(ctx context.Context, client corepb.CoreRPCClient, deployOpts *corepb.DeployOptions) error {
+// selectEntries returns the given entries, or all entries, in declared order of specs
+func selectEntries(specs *types.Specs, entries []string, all bool) ([]string, error) {
+	if all {
+		return specs.EntrypointNames, nil
+	}
+	selected := []string{}
+	for _, entry := range entries {
+		if _, ok := specs.Entrypoints[entry]; !ok {
+			return nil, fmt.Errorf("[Deploy] entry %s not found in specs", entry)
+		}
+	}
+	for _, name := range specs.EntrypointNames {
+		for _, entry := range entries {
+			if name == entry {
+				selected = append(selected, name)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// loadSpecs loads specs given by the first argument
+func loadSpecs(c *cli.Context) (*types.Specs, error) {
+	specURI := c.Args().First()
+	if specURI == "" {
+		return nil, fmt.Errorf("a specs must be given")
+	}
+	logrus.Debugf("[loadSpecs] Load %s", specURI)
+
+	data, err := utils.LoadSpec(specURI, c.StringSlice("values"), c.StringSlice("set"), c.StringSlice("overlay"))
+	if err != nil {
+		return nil, fmt.Errorf("[loadSpecs] load specs failed %v", err)
+	}
+
+	specs := &types.Specs{}
+	if err := yaml.Unmarshal(data, specs); err != nil {
+		return nil, fmt.Errorf("[loadSpecs] get specs failed %v", err)
+	}
+	return specs, nil
+}
+
+func doCreateWorkload(ctx context.Context, client corepb.CoreRPCClient, deployOpts *corepb.DeployOptions) ([]*corepb.CreateWorkloadMessage, error) {
 	resp, err := client.CreateWorkload(ctx, deployOpts)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	msgs := []*corepb.CreateWorkloadMessage{}
 	for {
 		msg, err := resp.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return err
+			return msgs, err
 		}
+		msgs = append(msgs, msg)
 
 		if msg.Success {
 			logrus.Infof("[Deploy] Success %s %s %s %s", msg.Id, msg.Name, msg.Nodename, msg.Resources)
@@ -120,54 +331,30 @@ func doCreateWorkload(ctx context.Context, client corepb.CoreRPCClient, deployOp
 			logrus.Errorf("[Deploy] Failed %v", msg.Error)
 		}
 	}
-	return nil
+	return msgs, nil
 }
 
-func generateDeployOptions(c *cli.Context) (*corepb.DeployOptions, error) {
-	specURI := c.Args().First()
-	if specURI == "" {
-		return nil, fmt.Errorf("a specs must be given")
-	}
-	logrus.Debugf("[Deploy] Deploy %s", specURI)
-
-	var (
-		data []byte
-		err  error
-	)
-	if strings.HasPrefix(specURI, "http") {
-		data, err = utils.GetSpecFromRemote(specURI)
-	} else {
-		data, err = ioutil.ReadFile(specURI)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	memoryRequest, memoryLimit, err := memoryOption(c)
-	if err != nil {
-		return nil, fmt.Errorf("[generateDeployOptions] parse memory failed %v", err)
+func generateDeployOptions(c *cli.Context, specs *types.Specs, entry string) (*corepb.DeployOptions, error) {
+	entrypoint, ok := specs.Entrypoints[entry]
+	if !ok {
+		return nil, fmt.Errorf("[generateDeployOptions] get entry failed")
 	}
 
-	storageRequest, storageLimit, err := storageOption(c)
+	r, err := entryResourceOptions(c, entrypoint.Deploy)
 	if err != nil {
-		return nil, fmt.Errorf("[generateDeployOptions] parse storage failed %v", err)
+		return nil, fmt.Errorf("[generateDeployOptions] parse resources of %s failed %v", entry, err)
 	}
 
-	cpuRequest, cpuLimit := cpuOption(c)
-
-	specs := &types.Specs{}
-	if err := yaml.Unmarshal(data, specs); err != nil {
-		return nil, fmt.Errorf("[generateDeployOptions] get specs failed %v", err)
+	network, count := c.String("network"), c.Int("count")
+	if d := entrypoint.Deploy; d != nil {
+		if d.Network != "" && !c.IsSet("network") {
+			network = d.Network
+		}
+		if d.Count > 0 && !c.IsSet("count") {
+			count = d.Count
+		}
 	}
-
-	entry := c.String("entry")
-
-	network := c.String("network")
 	networks := utils.GetNetworks(network)
-	entrypoint, ok := specs.Entrypoints[entry]
-	if !ok {
-		return nil, fmt.Errorf("[generateDeployOptions] get entry failed")
-	}
 
 	var hook *corepb.HookOptions
 	if entrypoint.Hook != nil {
@@ -205,14 +392,14 @@ func generateDeployOptions(c *cli.Context) (*corepb.DeployOptions, error) {
 	content, modes, owners := utils.GenerateFileOptions(c)
 
 	cpumem := resourcetypes.RawParams{
-		"cpu-request":    cpuRequest,
-		"cpu-limit":      cpuLimit,
-		"memory-request": memoryRequest,
-		"memory-limit":   memoryLimit,
+		"cpu-request":    r.CPURequest,
+		"cpu-limit":      r.CPULimit,
+		"memory-request": r.MemoryRequest,
+		"memory-limit":   r.MemoryLimit,
 	}
 	storage := resourcetypes.RawParams{
-		"storage-request": storageRequest,
-		"storage-limit":   storageLimit,
+		"storage-request": r.StorageRequest,
+		"storage-limit":   r.StorageLimit,
 		"volumes-request": specs.VolumesRequest,
 		"volumes-limit":   specs.Volumes,
 	}
@@ -262,7 +449,7 @@ func generateDeployOptions(c *cli.Context) (*corepb.DeployOptions, error) {
 			Labels:   utils.SplitEquality(c.StringSlice("nodelabel")),
 		},
 		Image:          c.String("image"),
-		Count:          int32(c.Int("count")),
+		Count:          int32(count),
 		Env:            c.StringSlice("env"),
 		Networks:       networks,
 		Labels:         specs.Labels,
@@ -280,3 +467,76 @@ func generateDeployOptions(c *cli.Context) (*corepb.DeployOptions, error) {
 		RawArgs:        rawArgsByte,
 	}, nil
 }
+
+// entryResourceOptions returns cpu, memory and storage request and limit of an entry,
+// for each of them, flags are used if any is given, or options in specs if given, or defaults of flags
+func entryResourceOptions(c *cli.Context, d *types.EntrypointDeploy) (*types.WorkloadResources, error) {
+	var err error
+	r := &types.WorkloadResources{}
+	r.CPURequest, r.CPULimit = cpuOption(c)
+	if r.MemoryRequest, r.MemoryLimit, err = memoryOption(c); err != nil {
+		return nil, err
+	}
+	if r.StorageRequest, r.StorageLimit, err = storageOption(c); err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return r, nil
+	}
+
+	isSet := func(names ...string) bool {
+		for _, name := range names {
+			if c.IsSet(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !isSet("cpu", "cpu-request", "cpu-limit") {
+		if d.CPURequest != 0 {
+			r.CPURequest = d.CPURequest
+		}
+		if d.CPULimit != 0 {
+			r.CPULimit = d.CPULimit
+		}
+		if d.CPU != 0 {
+			r.CPURequest, r.CPULimit = d.CPU, d.CPU
+		}
+	}
+	if !isSet("memory", "memory-request", "memory-limit") {
+		if r.MemoryRequest, r.MemoryLimit, err = sizeOptions(d.MemoryRequest, d.MemoryLimit, d.Memory, r.MemoryRequest, r.MemoryLimit); err != nil {
+			return nil, err
+		}
+	}
+	if !isSet("storage", "storage-request", "storage-limit") {
+		if r.StorageRequest, r.StorageLimit, err = sizeOptions(d.StorageRequest, d.StorageLimit, d.Storage, r.StorageRequest, r.StorageLimit); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// sizeOptions overrides request and limit with sizes in specs,
+// the shortcut overrides both like flags
+func sizeOptions(request, limit, shortcut string, defaultRequest, defaultLimit int64) (int64, int64, error) {
+	var err error
+	if request != "" {
+		if defaultRequest, err = utils.ParseRAMInHuman(request); err != nil {
+			return 0, 0, err
+		}
+	}
+	if limit != "" {
+		if defaultLimit, err = utils.ParseRAMInHuman(limit); err != nil {
+			return 0, 0, err
+		}
+	}
+	if shortcut != "" {
+		size, err := utils.ParseRAMInHuman(shortcut)
+		if err != nil {
+			return 0, 0, err
+		}
+		defaultRequest, defaultLimit = size, size
+	}
+	return defaultRequest, defaultLimit, nil
+}
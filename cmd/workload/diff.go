@@ -0,0 +1,254 @@
+package workload
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	resourcetypes "github.com/projecteru2/core/resource/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"github.com/juju/errors"
+	"github.com/urfave/cli/v2"
+)
+
+type diffWorkloadsOptions struct {
+	client corepb.CoreRPCClient
+	ids    [2]string
+	// files to fetch by Copy and diff
+	files []string
+	color bool
+}
+
+func (o *diffWorkloadsOptions) run(ctx context.Context) error {
+	resp, err := o.client.GetWorkloads(ctx, &corepb.WorkloadIDs{IDs: o.ids[:]})
+	if err != nil {
+		return err
+	}
+	workloads := map[string]*corepb.Workload{}
+	for _, w := range resp.Workloads {
+		workloads[w.Id] = w
+	}
+	a, b := workloads[o.ids[0]], workloads[o.ids[1]]
+	if a == nil || b == nil {
+		return fmt.Errorf("[Diff] workloads %s and %s not found", o.ids[0], o.ids[1])
+	}
+
+	fa, fb := workloadFields(a), workloadFields(b)
+	if len(o.files) > 0 {
+		contents, err := o.fetchFiles(ctx)
+		if err != nil {
+			return err
+		}
+		for _, path := range o.files {
+			field := "files/" + strings.TrimPrefix(path, "/")
+			fa[field] = contents[a.Id][path]
+			fb[field] = contents[b.Id][path]
+		}
+	}
+
+	names := []string{}
+	for name := range fa {
+		names = append(names, name)
+	}
+	for name := range fb {
+		if _, ok := fa[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diff := &types.WorkloadsDiff{
+		A:      &types.DiffedWorkload{ID: a.Id, Name: a.Name},
+		B:      &types.DiffedWorkload{ID: b.Id, Name: b.Name},
+		Fields: []*types.FieldDiff{},
+	}
+	for _, name := range names {
+		if strings.Join(fa[name], "\n") != strings.Join(fb[name], "\n") {
+			diff.Fields = append(diff.Fields, &types.FieldDiff{Field: name, A: fa[name], B: fb[name]})
+		}
+	}
+	describe.WorkloadsDiff(diff, o.color)
+
+	// exits with 1 if different, as diff does
+	if len(diff.Fields) > 0 {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// fetchFiles copies files from both workloads,
+// returns map workloadID -> path -> lines of content
+func (o *diffWorkloadsOptions) fetchFiles(ctx context.Context) (map[string]map[string][]string, error) {
+	targets := map[string]*corepb.CopyPaths{}
+	for _, id := range o.ids {
+		targets[id] = &corepb.CopyPaths{Paths: o.files}
+	}
+	resp, err := o.client.Copy(ctx, &corepb.CopyOptions{Targets: targets})
+	if err != nil {
+		return nil, err
+	}
+
+	tarballs := map[string]map[string]*bytes.Buffer{}
+	contents := map[string]map[string][]string{}
+	for _, id := range o.ids {
+		tarballs[id] = map[string]*bytes.Buffer{}
+		contents[id] = map[string][]string{}
+	}
+	for {
+		msg, err := resp.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tarballs[msg.Id]; !ok {
+			continue
+		}
+		if msg.Error != "" {
+			contents[msg.Id][msg.Path] = []string{fmt.Sprintf("<error: %s>", msg.Error)}
+			continue
+		}
+		buf, ok := tarballs[msg.Id][msg.Path]
+		if !ok {
+			buf = &bytes.Buffer{}
+			tarballs[msg.Id][msg.Path] = buf
+		}
+		buf.Write(msg.Data)
+	}
+
+	for id, paths := range tarballs {
+		for path, buf := range paths {
+			if _, ok := contents[id][path]; ok {
+				continue
+			}
+			lines, err := fileLines(buf)
+			if err != nil {
+				lines = []string{fmt.Sprintf("<error: %v>", err)}
+			}
+			contents[id][path] = lines
+		}
+	}
+	return contents, nil
+}
+
+// fileLines returns lines of the regular file in the tarball
+func fileLines(r io.Reader) ([]string, error) {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("no regular file found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		return splitLines(string(b)), nil
+	}
+}
+
+// workloadFields returns fields of a workload to diff, in lines,
+// lines of maps are sorted key=value
+func workloadFields(w *corepb.Workload) map[string][]string {
+	fields := map[string][]string{
+		"image":    {w.Image},
+		"podname":  {w.Podname},
+		"nodename": {w.Nodename},
+		"env":      sortedLines(w.Env),
+		"labels":   mapLines(w.Labels),
+		"publish":  mapLines(w.Publish),
+	}
+
+	resources := resourcetypes.Resources{}
+	if w.Resources != "" {
+		if err := json.Unmarshal([]byte(w.Resources), &resources); err != nil {
+			fields["resources"] = splitLines(w.Resources)
+		}
+	}
+	for plugin, params := range resources {
+		lines := []string{}
+		for key, value := range params {
+			b, _ := json.Marshal(value)
+			lines = append(lines, fmt.Sprintf("%s=%s", key, b))
+		}
+		fields["resources."+plugin] = sortedLines(lines)
+	}
+
+	if w.Status != nil {
+		fields["networks"] = mapLines(w.Status.Networks)
+		fields["status"] = []string{fmt.Sprintf("running=%v", w.Status.Running), fmt.Sprintf("healthy=%v", w.Status.Healthy)}
+		fields["status.extension"] = extensionLines(w.Status.Extension)
+	}
+	return fields
+}
+
+// extensionLines formats extension as indented json if it's json
+func extensionLines(extension []byte) []string {
+	var v any
+	if err := json.Unmarshal(extension, &v); err != nil {
+		return splitLines(string(extension))
+	}
+	b, _ := json.MarshalIndent(v, "", "  ")
+	return splitLines(string(b))
+}
+
+func mapLines(m map[string]string) []string {
+	lines := []string{}
+	for k, v := range m {
+		lines = append(lines, fmt.Sprintf("%s=%s", k, v))
+	}
+	return sortedLines(lines)
+}
+
+func sortedLines(lines []string) []string {
+	lines = append([]string{}, lines...)
+	sort.Strings(lines)
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func cmdWorkloadDiff(c *cli.Context) error {
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 2 {
+		return errors.New("Two workload IDs must be given")
+	}
+
+	var files []string
+	if v := c.String("files"); v != "" {
+		files = strings.Split(v, ",")
+	}
+
+	o := &diffWorkloadsOptions{
+		client: client,
+		ids:    [2]string{c.Args().Get(0), c.Args().Get(1)},
+		files:  files,
+		color:  c.Bool("color"),
+	}
+	return o.run(c.Context)
+}
@@ -1,16 +1,24 @@
 package workload
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 
 	"github.com/projecteru2/cli/cmd/utils"
 	"github.com/projecteru2/cli/interactive"
 	corepb "github.com/projecteru2/core/rpc/gen"
 
+	"github.com/juju/errors"
 	"github.com/urfave/cli/v2"
 )
 
+// exitDataPrefix prefixes the exit code sent by core after commands exit
+const exitDataPrefix = "[exitcode] "
+
 type execWorkloadOptions struct {
 	client      corepb.CoreRPCClient
 	id          string
@@ -18,9 +26,11 @@ type execWorkloadOptions struct {
 	commands    []string
 	envs        []string
 	workdir     string
+	recorder    *interactive.Recorder
 }
 
 func (o *execWorkloadOptions) run(ctx context.Context) error {
+	defer utils.SaveSessionRecording(o.recorder)
 	opts := &corepb.ExecuteWorkloadOptions{
 		WorkloadId: o.id,
 		OpenStdin:  o.interactive,
@@ -42,6 +52,7 @@ func (o *execWorkloadOptions) run(ctx context.Context) error {
 		Send: func(cmd []byte) error {
 			return resp.Send(&corepb.ExecuteWorkloadOptions{ReplCmd: cmd})
 		},
+		Recorder: o.recorder,
 	}
 
 	code, err := interactive.HandleStream(opts.OpenStdin, iStream, 1, false)
@@ -52,12 +63,63 @@ func (o *execWorkloadOptions) run(ctx context.Context) error {
 	return err
 }
 
+// runInWorkload executes commands in workload without stdin,
+// stdout and stderr are written line by line, returns the exit code of commands
+func runInWorkload(ctx context.Context, client corepb.CoreRPCClient, opts *corepb.ExecuteWorkloadOptions, stdout, stderr io.Writer) (int, error) {
+	resp, err := client.ExecuteWorkload(ctx)
+	if err != nil {
+		return -1, err
+	}
+	opts.OpenStdin = false
+	if err := resp.Send(opts); err != nil {
+		return -1, err
+	}
+
+	code, exited := -1, false
+	for {
+		msg, err := resp.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return -1, err
+		}
+
+		switch {
+		case msg.StdStreamType == corepb.StdStreamType_ERUERROR:
+			return -1, errors.New(string(msg.Data))
+		case msg.StdStreamType == corepb.StdStreamType_TYPEWORKLOADID:
+		case bytes.HasPrefix(msg.Data, []byte(exitDataPrefix)):
+			if code, err = strconv.Atoi(strings.TrimSpace(string(msg.Data[len(exitDataPrefix):]))); err != nil {
+				return -1, fmt.Errorf("invalid exit code %q", msg.Data)
+			}
+			exited = true
+		case msg.StdStreamType == corepb.StdStreamType_STDERR:
+			if _, err := stderr.Write(msg.Data); err != nil {
+				return -1, err
+			}
+		default:
+			if _, err := stdout.Write(msg.Data); err != nil {
+				return -1, err
+			}
+		}
+	}
+	if !exited {
+		return -1, fmt.Errorf("commands didn't exit normally in workload %s", opts.WorkloadId)
+	}
+	return code, nil
+}
+
 func cmdWorkloadExec(c *cli.Context) error {
 	client, err := utils.NewCoreRPCClient(c)
 	if err != nil {
 		return err
 	}
 
+	if isSelecting(c) {
+		return cmdWorkloadFanoutExec(c, client)
+	}
+
 	id := c.Args().First()
 	if id == "" {
 		return fmt.Errorf("Workload ID should not be empty")
@@ -68,6 +130,11 @@ func cmdWorkloadExec(c *cli.Context) error {
 		return fmt.Errorf("Commands should not be empty")
 	}
 
+	recorder, err := utils.NewSessionRecorder(c, c.Bool("interactive"), strings.Join(commands, " "))
+	if err != nil {
+		return err
+	}
+
 	o := &execWorkloadOptions{
 		client:      client,
 		id:          id,
@@ -75,6 +142,7 @@ func cmdWorkloadExec(c *cli.Context) error {
 		commands:    commands,
 		envs:        c.StringSlice("env"),
 		workdir:     c.String("workdir"),
+		recorder:    recorder,
 	}
 	return o.run(c.Context)
 }
@@ -0,0 +1,370 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var plainWord = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// labelFields are fields only kept by the entrypoint label, they're unknown for workloads without it
+var labelFields = []string{"commands", "dir", "restart", "sysctls", "hook", "log", "dns", "extra_hosts", "user"}
+
+type exportSpecOptions struct {
+	client corepb.CoreRPCClient
+	// name is appname, or ID of a workload
+	name    string
+	file    string
+	partial bool
+}
+
+// exportedEntry is an entrypoint rebuilt from its workloads
+type exportedEntry struct {
+	name       string
+	podname    string
+	image      string
+	network    string
+	env        []string
+	labels     map[string]string
+	entrypoint types.Entrypoint
+	resources  *types.WorkloadResources
+	label      *types.EntrypointLabel
+}
+
+func (o *exportSpecOptions) run(ctx context.Context) error {
+	workloads, err := o.workloads(ctx)
+	if err != nil {
+		return err
+	}
+	if len(workloads) == 0 {
+		return fmt.Errorf("[ExportSpec] no workloads found by %s", o.name)
+	}
+
+	appname, out, err := o.export(workloads)
+	if err != nil {
+		return err
+	}
+
+	if o.file == "" {
+		_, err = io.WriteString(os.Stdout, out)
+		return err
+	}
+	if err := os.WriteFile(o.file, []byte(out), 0644); err != nil {
+		return err
+	}
+	logrus.Infof("[ExportSpec] Specs of %s exported to %s", appname, o.file)
+	return nil
+}
+
+// export returns the spec rebuilt from workloads, with deploy commands as comments,
+// it fails if fields are not recovered, unless o.partial is set and they're listed as comments
+func (o *exportSpecOptions) export(workloads []*corepb.Workload) (string, string, error) {
+	appname, entries, err := exportEntries(workloads)
+	if err != nil {
+		return "", "", err
+	}
+	unknown := []string{}
+	for _, e := range entries {
+		if e.label == nil {
+			unknown = append(unknown, e.name)
+		}
+	}
+	if len(unknown) > 0 && !o.partial {
+		return "", "", fmt.Errorf("[ExportSpec] workloads of %s have no label %s, they're deployed by older cli, %s can't be recovered, use --partial to export the rest",
+			strings.Join(unknown, ", "), types.LabelEntrypoint, strings.Join(labelFields, ", "))
+	}
+	specs := exportSpecs(appname, entries)
+
+	b, err := yaml.Marshal(specsDocument(specs))
+	if err != nil {
+		return "", "", err
+	}
+	out := &strings.Builder{}
+	out.Write(b)
+	if len(unknown) > 0 {
+		out.WriteString("\n# not recovered, fill them before deploying:\n")
+		for _, name := range unknown {
+			fmt.Fprintf(out, "# %s: %s\n", name, strings.Join(labelFields, ", "))
+		}
+	}
+	out.WriteString("\n# deploy with:\n")
+	for _, e := range entries {
+		fmt.Fprintf(out, "# %s\n", deployCommand(e, o.file))
+	}
+	return appname, out.String(), nil
+}
+
+// workloads returns workloads of the app, or the workload if name is not an app
+func (o *exportSpecOptions) workloads(ctx context.Context) ([]*corepb.Workload, error) {
+	workloads, err := (&workloadSelector{appname: o.name}).list(ctx, o.client)
+	if err != nil || len(workloads) > 0 {
+		return workloads, err
+	}
+	w, err := o.client.GetWorkload(ctx, &corepb.WorkloadID{Id: o.name})
+	if err != nil {
+		return nil, fmt.Errorf("[ExportSpec] %s is neither an app nor a workload: %v", o.name, err)
+	}
+	return []*corepb.Workload{w}, nil
+}
+
+// exportEntries groups workloads by entrypoint, the latest created workload of each entrypoint
+// is used to rebuild it, entrypoints are sorted by name
+func exportEntries(workloads []*corepb.Workload) (string, []*exportedEntry, error) {
+	appname := ""
+	groups := map[string][]*corepb.Workload{}
+	for _, w := range workloads {
+		app, entry, _, err := coreutils.ParseWorkloadName(w.Name)
+		if err != nil {
+			return "", nil, err
+		}
+		if appname != "" && app != appname {
+			return "", nil, fmt.Errorf("[ExportSpec] workloads of different apps %s and %s", appname, app)
+		}
+		appname = app
+		groups[entry] = append(groups[entry], w)
+	}
+
+	entries := []*exportedEntry{}
+	for name, ws := range groups {
+		sort.Slice(ws, func(i, j int) bool { return ws[i].CreateTime > ws[j].CreateTime })
+		for _, w := range ws[1:] {
+			if w.Image != ws[0].Image {
+				logrus.Warnf("[ExportSpec] Workloads of %s run different images, %s of the latest one is exported", name, ws[0].Image)
+				break
+			}
+		}
+		e, err := exportEntry(name, ws[0])
+		if err != nil {
+			return "", nil, err
+		}
+		e.entrypoint.Deploy.Count = len(ws)
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return appname, entries, nil
+}
+
+func exportEntry(name string, w *corepb.Workload) (*exportedEntry, error) {
+	e := &exportedEntry{
+		name:    name,
+		podname: w.Podname,
+		image:   w.Image,
+		env:     utils.DeployedEnv(w.Env),
+		labels:  exportLabels(w.Labels),
+	}
+
+	resources, err := types.ParseWorkloadResources(w.Resources)
+	if err != nil {
+		return nil, fmt.Errorf("[ExportSpec] invalid resources of %s: %v", w.Name, err)
+	}
+	e.resources = resources
+
+	if w.Status != nil && len(w.Status.Networks) > 0 {
+		networks := []string{}
+		for network := range w.Status.Networks {
+			networks = append(networks, network)
+		}
+		sort.Strings(networks)
+		e.network = networks[0]
+		if len(networks) > 1 {
+			logrus.Warnf("[ExportSpec] %s is in networks %v, only %s is exported", w.Name, networks, e.network)
+		}
+	}
+
+	e.entrypoint.Privileged = w.Privileged
+	m, err := utils.WorkloadMeta(w)
+	if err != nil {
+		logrus.Warnf("[ExportSpec] %v", err)
+	}
+	if m != nil {
+		e.entrypoint.Publish = m.Publish
+		e.entrypoint.HealthCheck = m.HealthCheck
+	}
+	if e.label, err = utils.WorkloadEntrypointLabel(w); err != nil {
+		return nil, fmt.Errorf("[ExportSpec] %v", err)
+	}
+	if e.label != nil {
+		e.entrypoint.Commands = e.label.Commands
+		e.entrypoint.Dir = e.label.Dir
+		e.entrypoint.Restart = e.label.Restart
+		e.entrypoint.Sysctls = e.label.Sysctls
+		e.entrypoint.Hook = e.label.Hook
+		e.entrypoint.Log = e.label.Log
+	}
+
+	e.entrypoint.Deploy = &types.EntrypointDeploy{
+		Network:    e.network,
+		CPURequest: resources.CPURequest,
+		CPULimit:   resources.CPULimit,
+	}
+	// zero values are left empty to be omitted
+	for _, f := range []struct {
+		field *string
+		value int64
+	}{
+		{&e.entrypoint.Deploy.MemoryRequest, resources.MemoryRequest},
+		{&e.entrypoint.Deploy.MemoryLimit, resources.MemoryLimit},
+		{&e.entrypoint.Deploy.StorageRequest, resources.StorageRequest},
+		{&e.entrypoint.Deploy.StorageLimit, resources.StorageLimit},
+	} {
+		if f.value != 0 {
+			*f.field = exportBytes(f.value)
+		}
+	}
+	return e, nil
+}
+
+// exportSpecs merges entrypoints into specs,
+// labels, dns, extra hosts and volumes are app level in specs
+func exportSpecs(appname string, entries []*exportedEntry) *types.Specs {
+	specs := &types.Specs{
+		Appname:     appname,
+		Entrypoints: map[string]types.Entrypoint{},
+		Labels:      map[string]string{},
+	}
+	for _, e := range entries {
+		specs.Entrypoints[e.name] = e.entrypoint
+		for k, v := range e.labels {
+			specs.Labels[k] = v
+		}
+		specs.EntrypointNames = append(specs.EntrypointNames, e.name)
+		if e.label != nil && len(specs.DNS) == 0 && len(specs.ExtraHosts) == 0 {
+			specs.DNS = e.label.DNS
+			specs.ExtraHosts = e.label.ExtraHosts
+		}
+		if len(specs.Volumes) == 0 && len(specs.VolumesRequest) == 0 {
+			specs.Volumes = e.resources.VolumesLimit
+			specs.VolumesRequest = e.resources.VolumesRequest
+		} else if strings.Join(specs.Volumes, ",") != strings.Join(e.resources.VolumesLimit, ",") {
+			logrus.Warnf("[ExportSpec] Volumes of %s differ from other entrypoints, they're not exported", e.name)
+		}
+	}
+	return specs
+}
+
+// specsDocument keeps the order of keys as app.yaml usually does
+func specsDocument(specs *types.Specs) yaml.MapSlice {
+	doc := yaml.MapSlice{{Key: "appname", Value: specs.Appname}}
+	entrypoints := yaml.MapSlice{}
+	for _, name := range specs.EntrypointNames {
+		entrypoints = append(entrypoints, yaml.MapItem{Key: name, Value: specs.Entrypoints[name]})
+	}
+	doc = append(doc, yaml.MapItem{Key: "entrypoints", Value: entrypoints})
+
+	for _, item := range []struct {
+		key   string
+		value any
+		empty bool
+	}{
+		{"volumes", specs.Volumes, len(specs.Volumes) == 0},
+		{"volumes_request", specs.VolumesRequest, len(specs.VolumesRequest) == 0},
+		{"labels", specs.Labels, len(specs.Labels) == 0},
+		{"dns", specs.DNS, len(specs.DNS) == 0},
+		{"extra_hosts", specs.ExtraHosts, len(specs.ExtraHosts) == 0},
+	} {
+		if !item.empty {
+			doc = append(doc, yaml.MapItem{Key: item.key, Value: item.value})
+		}
+	}
+	return doc
+}
+
+// deployCommand returns the command line to deploy the entrypoint as it is
+func deployCommand(e *exportedEntry, file string) string {
+	if file == "" {
+		file = "app.yaml"
+	}
+	args := []string{"eru-cli", "workload", "deploy", "--pod", e.podname, "--entry", e.name, "--image", e.image, "--count", strconv.Itoa(e.entrypoint.Deploy.Count)}
+	if e.network != "" {
+		args = append(args, "--network", e.network)
+	}
+	r := e.resources
+	args = append(args,
+		"--cpu-request", strconv.FormatFloat(r.CPURequest, 'f', -1, 64),
+		"--cpu-limit", strconv.FormatFloat(r.CPULimit, 'f', -1, 64),
+		"--memory-request", exportBytes(r.MemoryRequest),
+		"--memory-limit", exportBytes(r.MemoryLimit),
+	)
+	if r.StorageRequest > 0 || r.StorageLimit > 0 {
+		args = append(args, "--storage-request", exportBytes(r.StorageRequest), "--storage-limit", exportBytes(r.StorageLimit))
+	}
+	if r.CPUBind {
+		args = append(args, "--cpu-bind")
+	}
+	if e.label != nil && e.label.User != "" {
+		args = append(args, "--user", e.label.User)
+	}
+	for _, env := range e.env {
+		args = append(args, "--env", env)
+	}
+	args = append(args, file)
+
+	for i, arg := range args {
+		if !plainWord.MatchString(arg) {
+			args[i] = utils.ShellQuote(arg)
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// exportLabels returns labels given in specs,
+// labels of eru-core and reserved ones are excluded
+func exportLabels(labels map[string]string) map[string]string {
+	exported := map[string]string{}
+	for k, v := range labels {
+		if utils.IsCoreLabel(k) || types.IsReservedLabel(k) {
+			continue
+		}
+		exported[k] = v
+	}
+	return exported
+}
+
+// exportBytes formats bytes exactly in the biggest unit, like 512M
+func exportBytes(n int64) string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}} {
+		if n != 0 && n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func cmdWorkloadExportSpec(c *cli.Context) error {
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Appname or workload ID must be given")
+	}
+
+	o := &exportSpecOptions{
+		client:  client,
+		name:    name,
+		file:    c.String("file"),
+		partial: c.Bool("partial"),
+	}
+	return o.run(c.Context)
+}
@@ -0,0 +1,66 @@
+package workload
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+)
+
+func exportWorkloads() []*corepb.Workload {
+	return []*corepb.Workload{
+		{
+			Name:      "test_http_RfKuXJ",
+			Podname:   "muroq",
+			Image:     "python:3.10",
+			Labels:    map[string]string{types.LabelEntrypoint: `{"commands":["python3 -m http.server"],"restart":"always"}`, "team": "infra"},
+			Resources: `{"cpumem": {"cpu_request": 1, "cpu_limit": 1}}`,
+			Status:    &corepb.WorkloadStatus{Networks: map[string]string{"host": ""}},
+		},
+		{
+			Name:    "test_old_tPbnYc",
+			Podname: "muroq",
+			Image:   "python:3.10",
+		},
+	}
+}
+
+func TestExportSpecWithoutLabel(t *testing.T) {
+	o := &exportSpecOptions{}
+	_, _, err := o.export(exportWorkloads())
+	if err == nil || !strings.Contains(err.Error(), "workloads of old have no label") || !strings.Contains(err.Error(), "commands") {
+		t.Fatalf("export() error = %v, should fail with entrypoints and fields not recovered", err)
+	}
+
+	o.partial = true
+	appname, out, err := o.export(exportWorkloads())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appname != "test" {
+		t.Errorf("appname = %s", appname)
+	}
+	for _, s := range []string{
+		"- python3 -m http.server",
+		"restart: always",
+		"team: infra",
+		"# not recovered, fill them before deploying:\n# old: commands, dir, restart, sysctls, hook, log, dns, extra_hosts, user\n",
+		"# eru-cli workload deploy --pod muroq --entry http --image python:3.10 --count 1 --network host",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("exported spec has no %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestExportSpec(t *testing.T) {
+	o := &exportSpecOptions{}
+	_, out, err := o.export(exportWorkloads()[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "not recovered") {
+		t.Errorf("all fields are recovered, got:\n%s", out)
+	}
+}
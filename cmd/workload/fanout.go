@@ -0,0 +1,191 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// fanoutExecOptions executes commands in all selected workloads
+type fanoutExecOptions struct {
+	client   corepb.CoreRPCClient
+	selector *workloadSelector
+	// how many workloads to execute at the same time
+	parallel int
+	// print output with workload name prefixed as they come
+	stream bool
+
+	commands []string
+	envs     []string
+	workdir  string
+}
+
+func (o *fanoutExecOptions) run(ctx context.Context) error {
+	workloads, err := o.selector.list(ctx, o.client)
+	if err != nil {
+		return err
+	}
+	if len(workloads) == 0 {
+		return fmt.Errorf("[Exec] no workloads selected")
+	}
+	logrus.Infof("[Exec] Execute %v in %d workload(s)", o.commands, len(workloads))
+
+	parallel := o.parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+	results := make([]*types.ExecResult, len(workloads))
+	sem := make(chan struct{}, parallel)
+	mu := &sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i, w := range workloads {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, w *corepb.Workload) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = o.execute(ctx, w, mu)
+		}(i, w)
+	}
+	wg.Wait()
+
+	summary := &types.ExecSummary{Results: results, Groups: groupExecResults(results)}
+	describe.ExecSummary(summary)
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" || r.ExitCode != 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("[Exec] %d of %d workload(s) failed", failed, len(results)), partialFailureExitCode)
+	}
+	return nil
+}
+
+// execute runs commands in a workload, output is prefixed with the name of workload,
+// lines of different workloads are not mixed up since they're written under mu
+func (o *fanoutExecOptions) execute(ctx context.Context, w *corepb.Workload, mu *sync.Mutex) *types.ExecResult {
+	result := &types.ExecResult{ID: w.Id, Name: w.Name, Nodename: w.Nodename}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	var outWriter, errWriter io.Writer = stdout, stderr
+	if o.stream {
+		outPrefix := &prefixWriter{prefix: "[" + w.Name + "] ", w: os.Stdout, mu: mu}
+		errPrefix := &prefixWriter{prefix: "[" + w.Name + "] ", w: os.Stderr, mu: mu}
+		defer outPrefix.flush() //nolint
+		defer errPrefix.flush() //nolint
+		outWriter = io.MultiWriter(stdout, outPrefix)
+		errWriter = io.MultiWriter(stderr, errPrefix)
+	}
+
+	code, err := runInWorkload(ctx, o.client, &corepb.ExecuteWorkloadOptions{
+		WorkloadId: w.Id,
+		Commands:   o.commands,
+		Envs:       o.envs,
+		Workdir:    o.workdir,
+	}, outWriter, errWriter)
+	result.ExitCode = code
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	hash := sha256.New()
+	hash.Write(stdout.Bytes())
+	hash.Write(stderr.Bytes())
+	result.OutputHash = hex.EncodeToString(hash.Sum(nil))[:12]
+	return result
+}
+
+// groupExecResults groups results by exit code, output hash and error,
+// the largest group comes first, it's usually the expected one
+func groupExecResults(results []*types.ExecResult) []*types.ExecGroup {
+	groups := []*types.ExecGroup{}
+	index := map[string]*types.ExecGroup{}
+	for _, r := range results {
+		key := fmt.Sprintf("%d/%s/%s", r.ExitCode, r.OutputHash, r.Error)
+		g, ok := index[key]
+		if !ok {
+			g = &types.ExecGroup{ExitCode: r.ExitCode, OutputHash: r.OutputHash, Error: r.Error}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.Workloads = append(g.Workloads, r.Name)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Workloads) > len(groups[j].Workloads) })
+	return groups
+}
+
+// prefixWriter prefixes each line written to w
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.mu.Lock()
+		_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, p.buf[:i+1])
+		p.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// flush writes the last line not terminated by newline
+func (p *prefixWriter) flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf)
+	p.buf = nil
+	return err
+}
+
+func cmdWorkloadFanoutExec(c *cli.Context, client corepb.CoreRPCClient) error {
+	if c.Bool("interactive") {
+		return fmt.Errorf("[Exec] interactive is not supported with multiple workloads")
+	}
+	commands := c.Args().Slice()
+	if len(commands) == 0 {
+		return fmt.Errorf("Commands should not be empty")
+	}
+
+	o := &fanoutExecOptions{
+		client:   client,
+		selector: newWorkloadSelector(c),
+		parallel: c.Int("parallel"),
+		stream:   !describe.IsStructured(),
+		commands: commands,
+		envs:     c.StringSlice("env"),
+		workdir:  c.String("workdir"),
+	}
+	return o.run(c.Context)
+}
@@ -0,0 +1,71 @@
+package workload
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+)
+
+func TestGroupExecResults(t *testing.T) {
+	results := []*types.ExecResult{
+		{Name: "a", ExitCode: 1, OutputHash: "x"},
+		{Name: "b", ExitCode: 0, OutputHash: "y"},
+		{Name: "c", ExitCode: 0, OutputHash: "y"},
+		{Name: "d", ExitCode: 0, OutputHash: "z"},
+		{Name: "e", ExitCode: 0, OutputHash: "y", Error: "broken"},
+		{Name: "f", ExitCode: 1, OutputHash: "x"},
+		{Name: "g", ExitCode: 0, OutputHash: "y"},
+	}
+	groups := groupExecResults(results)
+	expected := []*types.ExecGroup{
+		{ExitCode: 0, OutputHash: "y", Workloads: []string{"b", "c", "g"}},
+		{ExitCode: 1, OutputHash: "x", Workloads: []string{"a", "f"}},
+		{ExitCode: 0, OutputHash: "z", Workloads: []string{"d"}},
+		{ExitCode: 0, OutputHash: "y", Error: "broken", Workloads: []string{"e"}},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		for _, g := range groups {
+			t.Logf("%+v", g)
+		}
+		t.Error("groupExecResults() is not as expected")
+	}
+	if groups := groupExecResults(nil); len(groups) != 0 {
+		t.Errorf("groupExecResults(nil) = %v", groups)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	mu := &sync.Mutex{}
+	a := &prefixWriter{prefix: "[a] ", w: out, mu: mu}
+	b := &prefixWriter{prefix: "[b] ", w: out, mu: mu}
+
+	for _, write := range []struct {
+		w    *prefixWriter
+		data string
+	}{
+		{a, "hel"},
+		{b, "one\ntw"},
+		{a, "lo\nworld\n"},
+		{b, "o\n\n"},
+		{a, "no newline"},
+	} {
+		if n, err := write.w.Write([]byte(write.data)); err != nil || n != len(write.data) {
+			t.Fatalf("Write() = %d, %v", n, err)
+		}
+	}
+	if err := a.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[b] one\n[a] hello\n[a] world\n[b] two\n[b] \n[a] no newline\n"
+	if out.String() != expected {
+		t.Errorf("output = %q, want %q", out.String(), expected)
+	}
+}
@@ -0,0 +1,183 @@
+package workload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"github.com/getlantern/deepcopy"
+)
+
+const journalDir = "journal"
+
+// newJournal snapshots workloads going to be replaced,
+// contents of files shipped are not kept, they may be secrets or large
+func newJournal(ctx context.Context, client corepb.CoreRPCClient, opts *corepb.ReplaceOptions) (*types.Journal, error) {
+	now := time.Now()
+	deployOpts := opts.DeployOpt
+	journalOpts := &corepb.ReplaceOptions{}
+	if err := deepcopy.Copy(journalOpts, opts); err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for name, content := range deployOpts.Data {
+		sum := sha256.Sum256(content)
+		files[name] = hex.EncodeToString(sum[:])
+	}
+	journalOpts.DeployOpt.Data = nil
+
+	journal := &types.Journal{
+		ID:         fmt.Sprintf("%s%03d-%s-%s", now.Format("20060102150405"), now.Nanosecond()/int(time.Millisecond), deployOpts.Name, deployOpts.Entrypoint.Name),
+		CreatedAt:  now,
+		Appname:    deployOpts.Name,
+		Entrypoint: deployOpts.Entrypoint.Name,
+		Image:      deployOpts.Image,
+		Options:    journalOpts,
+		Files:      files,
+	}
+
+	workloads, err := replacingWorkloads(ctx, client, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workloads {
+		journal.Entries = append(journal.Entries, &types.JournalEntry{
+			OldID:      w.Id,
+			OldName:    w.Name,
+			Nodename:   w.Nodename,
+			Privileged: w.Privileged,
+			Image:      w.Image,
+			Env:        w.Env,
+			Labels:     w.Labels,
+			Resources:  w.Resources,
+		})
+	}
+	return journal, nil
+}
+
+// replacingWorkloads returns workloads selected by replace options, the same way as core does
+func replacingWorkloads(ctx context.Context, client corepb.CoreRPCClient, opts *corepb.ReplaceOptions) ([]*corepb.Workload, error) {
+	if len(opts.IDs) > 0 {
+		resp, err := client.GetWorkloads(ctx, &corepb.WorkloadIDs{IDs: opts.IDs})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Workloads, nil
+	}
+
+	deployOpts := opts.DeployOpt
+	nodenames := []string{""}
+	if deployOpts.NodeFilter != nil && len(deployOpts.NodeFilter.Includes) > 0 {
+		nodenames = deployOpts.NodeFilter.Includes
+	}
+	workloads := []*corepb.Workload{}
+	for _, nodename := range nodenames {
+		resp, err := client.ListWorkloads(ctx, &corepb.ListWorkloadsOptions{
+			Appname:    deployOpts.Name,
+			Entrypoint: deployOpts.Entrypoint.Name,
+			Nodename:   nodename,
+			Labels:     opts.FilterLabels,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for {
+			w, err := resp.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			workloads = append(workloads, w)
+		}
+	}
+	return workloads, nil
+}
+
+// recordJournal updates the entry of the replaced workload and saves the journal
+func recordJournal(journal *types.Journal, msg *corepb.ReplaceWorkloadMessage) error {
+	if msg.Remove == nil {
+		return nil
+	}
+	var entry *types.JournalEntry
+	for _, e := range journal.Entries {
+		if e.OldID == msg.Remove.Id {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		// not listed before replacing, record what we know
+		entry = &types.JournalEntry{OldID: msg.Remove.Id}
+		journal.Entries = append(journal.Entries, entry)
+	}
+	entry.Error = msg.Error
+	if msg.Create != nil && msg.Create.Success {
+		entry.NewID = msg.Create.Id
+		entry.NewName = msg.Create.Name
+	}
+	return saveJournal(journal)
+}
+
+func saveJournal(journal *types.Journal) error {
+	dir, err := utils.StateDir(journalDir)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, journal.ID+".json"), b, 0600)
+}
+
+func loadJournal(id string) (*types.Journal, error) {
+	dir, err := utils.StateDir(journalDir)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(dir, filepath.Base(id)+".json"))
+	if err != nil {
+		return nil, err
+	}
+	journal := &types.Journal{}
+	return journal, json.Unmarshal(b, journal)
+}
+
+// listJournals returns journals, latest first
+func listJournals() ([]*types.Journal, error) {
+	dir, err := utils.StateDir(journalDir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	journals := []*types.Journal{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		journal, err := loadJournal(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("[listJournals] load %s failed %v", f.Name(), err)
+		}
+		journals = append(journals, journal)
+	}
+	sort.Slice(journals, func(i, j int) bool { return journals[i].CreatedAt.After(journals[j].CreatedAt) })
+	return journals, nil
+}
@@ -0,0 +1,68 @@
+package workload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"google.golang.org/grpc"
+)
+
+type journalClient struct {
+	corepb.CoreRPCClient
+}
+
+func (c *journalClient) GetWorkloads(_ context.Context, in *corepb.WorkloadIDs, _ ...grpc.CallOption) (*corepb.Workloads, error) {
+	resp := &corepb.Workloads{}
+	for _, id := range in.IDs {
+		resp.Workloads = append(resp.Workloads, &corepb.Workload{Id: id, Name: "test_http_" + id})
+	}
+	return resp, nil
+}
+
+func TestJournalKeepsNoFileContents(t *testing.T) {
+	t.Setenv("ERU_CLI_HOME", t.TempDir())
+	opts := &corepb.ReplaceOptions{
+		DeployOpt: &corepb.DeployOptions{
+			Name:       "test",
+			Entrypoint: &corepb.EntrypointOptions{Name: "http"},
+			Data:       map[string][]byte{"/etc/secret": []byte("password")},
+		},
+		IDs: []string{"abc"},
+	}
+	journal, err := newJournal(context.Background(), &journalClient{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveJournal(journal); err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.DeployOpt.Data == nil {
+		t.Error("options to replace are changed")
+	}
+	dir := filepath.Join(os.Getenv("ERU_CLI_HOME"), journalDir)
+	b, err := os.ReadFile(filepath.Join(dir, journal.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "cGFzc3dvcmQ") || strings.Contains(string(b), "password") {
+		t.Errorf("contents of files are saved: %s", b)
+	}
+
+	loaded, err := loadJournal(journal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sha256 of "password"
+	if sum := loaded.Files["/etc/secret"]; sum != "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8" {
+		t.Errorf("files = %v", loaded.Files)
+	}
+	if len(loaded.Entries) != 1 || loaded.Entries[0].OldName != "test_http_abc" {
+		t.Errorf("entries = %v", loaded.Entries)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/projecteru2/cli/describe"
 	corepb "github.com/projecteru2/core/rpc/gen"
 
+	"github.com/juju/errors"
 	"github.com/urfave/cli/v2"
 )
 
@@ -25,6 +26,8 @@ type listWorkloadsOptions struct {
 	skipIPs    []string
 	podnames   []string
 	statistics bool
+	groupBy    string
+	groupKey   groupKey
 }
 
 func (o *listWorkloadsOptions) run(ctx context.Context) error {
@@ -69,7 +72,7 @@ func (o *listWorkloadsOptions) run(ctx context.Context) error {
 	workloads = f.filterIn(workloads)
 
 	if o.statistics {
-		describe.WorkloadsStatistics(workloads...)
+		describe.WorkloadsStatistics(o.groupBy, workloadsStatistics(workloads, o.groupKey)...)
 	} else {
 		describe.Workloads(workloads...)
 	}
@@ -138,6 +141,14 @@ func cmdWorkloadList(c *cli.Context) error {
 		return err
 	}
 
+	if c.IsSet("group-by") && !c.Bool("statistics") {
+		return errors.New("--group-by must be used with --statistics")
+	}
+	groupKey, err := parseGroupBy(c.String("group-by"))
+	if err != nil {
+		return err
+	}
+
 	o := &listWorkloadsOptions{
 		client:     client,
 		appname:    c.Args().First(),
@@ -149,6 +160,8 @@ func cmdWorkloadList(c *cli.Context) error {
 		skipIPs:    c.StringSlice("skip-ip"),
 		podnames:   c.StringSlice("pod"),
 		statistics: c.Bool("statistics"),
+		groupBy:    c.String("group-by"),
+		groupKey:   groupKey,
 	}
 	return o.run(c.Context)
 }
@@ -0,0 +1,192 @@
+package workload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+)
+
+// showPlan renders the resolved deploy options,
+// and diffs them against existing workloads of the same app/entry
+func showPlan(ctx context.Context, client corepb.CoreRPCClient, opts *corepb.DeployOptions) error {
+	plan := newDeployPlan(opts)
+
+	resp, err := client.ListWorkloads(ctx, &corepb.ListWorkloadsOptions{
+		Appname:    opts.Name,
+		Entrypoint: opts.Entrypoint.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("[Plan] list workloads failed %v", err)
+	}
+	for {
+		w, err := resp.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		plan.Existing++
+		diffs, err := diffWorkload(w, opts)
+		if err != nil {
+			return fmt.Errorf("[Plan] diff workload %s failed %v", w.Id, err)
+		}
+		plan.Diffs = append(plan.Diffs, diffs...)
+	}
+
+	describe.DeployPlan(plan)
+	return nil
+}
+
+func newDeployPlan(opts *corepb.DeployOptions) *types.DeployPlan {
+	resources := map[string]any{}
+	for plugin, b := range opts.Resources {
+		var params any
+		if err := json.Unmarshal(b, &params); err != nil {
+			params = string(b)
+		}
+		resources[plugin] = params
+	}
+
+	files := []*types.PlanFile{}
+	for path, content := range opts.Data {
+		sum := sha256.Sum256(content)
+		f := &types.PlanFile{
+			Path:   path,
+			Size:   len(content),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+		if mode, ok := opts.Modes[path]; ok && mode != nil && mode.Mode != 0 {
+			f.Mode = fmt.Sprintf("%o", mode.Mode)
+		}
+		if owner, ok := opts.Owners[path]; ok && owner != nil {
+			f.UID = owner.Uid
+			f.GID = owner.Gid
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	entry := opts.Entrypoint
+	plan := &types.DeployPlan{
+		Appname:     opts.Name,
+		Entrypoint:  entry.Name,
+		Podname:     opts.Podname,
+		Image:       opts.Image,
+		Count:       opts.Count,
+		Strategy:    opts.DeployStrategy.String(),
+		User:        opts.User,
+		Commands:    entry.Commands,
+		Dir:         entry.Dir,
+		Privileged:  entry.Privileged,
+		Restart:     entry.Restart,
+		Env:         opts.Env,
+		Networks:    opts.Networks,
+		Publish:     entry.Publish,
+		Labels:      opts.Labels,
+		Resources:   resources,
+		Files:       files,
+		Hook:        entry.Hook,
+		HealthCheck: entry.Healthcheck,
+		AfterCreate: opts.AfterCreate,
+	}
+	if opts.NodeFilter != nil {
+		plan.Nodes = opts.NodeFilter.Includes
+		plan.NodeLabels = opts.NodeFilter.Labels
+	}
+	return plan
+}
+
+// diffWorkload compares image, env, resource requests and labels
+// of an existing workload with the deploy options
+func diffWorkload(w *corepb.Workload, opts *corepb.DeployOptions) ([]*types.PlanDiff, error) {
+	diffs := []*types.PlanDiff{}
+	add := func(field, current, planned string) {
+		diffs = append(diffs, &types.PlanDiff{
+			ID:      w.Id,
+			Name:    w.Name,
+			Field:   field,
+			Current: current,
+			Planned: planned,
+		})
+	}
+
+	if w.Image != opts.Image {
+		add("image", w.Image, opts.Image)
+	}
+
+	removed, added := diffStrings(w.Env, opts.Env)
+	if len(removed) > 0 || len(added) > 0 {
+		add("env", strings.Join(removed, "\n"), strings.Join(added, "\n"))
+	}
+
+	current, err := types.ParseWorkloadResources(w.Resources)
+	if err != nil {
+		return nil, err
+	}
+	planned, err := types.ParseDeployResources(opts.Resources)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range []struct {
+		field            string
+		current, planned any
+	}{
+		{"cpu_request", current.CPURequest, planned.CPURequest},
+		{"cpu_limit", current.CPULimit, planned.CPULimit},
+		{"memory_request", current.MemoryRequest, planned.MemoryRequest},
+		{"memory_limit", current.MemoryLimit, planned.MemoryLimit},
+		{"storage_request", current.StorageRequest, planned.StorageRequest},
+		{"storage_limit", current.StorageLimit, planned.StorageLimit},
+	} {
+		if c, p := fmt.Sprint(r.current), fmt.Sprint(r.planned); c != p {
+			add(r.field, c, p)
+		}
+	}
+
+	keys := []string{}
+	for key := range opts.Labels {
+		// revision labels always change
+		if types.IsReservedLabel(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := w.Labels[key]; !ok || value != opts.Labels[key] {
+			add("labels."+key, value, opts.Labels[key])
+		}
+	}
+	return diffs, nil
+}
+
+// diffStrings returns elements only in a, and elements only in b
+func diffStrings(a, b []string) (onlyA, onlyB []string) {
+	inA := map[string]bool{}
+	for _, s := range a {
+		inA[s] = true
+	}
+	inB := map[string]bool{}
+	for _, s := range b {
+		inB[s] = true
+		if !inA[s] {
+			onlyB = append(onlyB, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s] {
+			onlyA = append(onlyA, s)
+		}
+	}
+	return onlyA, onlyB
+}
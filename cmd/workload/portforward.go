@@ -0,0 +1,233 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/sethvargo/go-signalcontext"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// escapeCommand makes core close stdin of exec
+var escapeCommand = []byte{0x1d}
+
+// relayScript relays between stdin and a port in workload.
+// stdin of exec is always a tty, and messages starting with 0x80 or 0x1d are taken by core as commands,
+// so input is sent as lines of base64 and decoded line by line,
+// output is written to the tty in raw mode, which keeps bytes as they are.
+// relayReady is printed after the tty is raw, input sent before would be echoed
+const relayScript = `stty raw -echo 2>/dev/null
+printf '%[2]s'
+if command -v socat >/dev/null 2>&1; then relay="socat - TCP:127.0.0.1:%[1]d"; else relay="nc 127.0.0.1 %[1]d"; fi
+while IFS= read -r line; do printf '%%s' "$line" | base64 -d; done | $relay 2>/dev/null`
+
+const (
+	relayReady = "ERU-CLI-PORT-FORWARD-READY\n"
+	// relayChunkSize is encoded into 3 KiB of base64, shorter than 4095 bytes which a tty line can hold
+	relayChunkSize = 2304
+)
+
+type portForwardOptions struct {
+	client corepb.CoreRPCClient
+	id     string
+	// local address to listen
+	address string
+	// local port -> port in workload
+	ports [][2]int
+}
+
+func (o *portForwardOptions) run(ctx context.Context) error {
+	ctx, cancel := signalcontext.Wrap(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	for _, ports := range o.ports {
+		l, err := net.Listen("tcp", net.JoinHostPort(o.address, strconv.Itoa(ports[0])))
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		logrus.Infof("[PortForward] Forwarding from %s to %s:%d", l.Addr(), coreutils.ShortID(o.id), ports[1])
+
+		wg.Add(1)
+		go func(l net.Listener, port int) {
+			defer wg.Done()
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					if ctx.Err() == nil {
+						logrus.Errorf("[PortForward] Accept failed %v", err)
+					}
+					return
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := o.forward(ctx, conn, port); err != nil {
+						logrus.Errorf("[PortForward] Forward %s failed %v", conn.RemoteAddr(), err)
+					}
+				}()
+			}
+		}(l, ports[1])
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// forward relays a connection through an exec session
+func (o *portForwardOptions) forward(ctx context.Context, conn net.Conn, port int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+	logrus.Debugf("[PortForward] Handle connection from %s", conn.RemoteAddr())
+
+	stream, err := o.client.ExecuteWorkload(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&corepb.ExecuteWorkloadOptions{
+		WorkloadId: o.id,
+		Commands:   []string{"sh", "-c", fmt.Sprintf(relayScript, port, relayReady)},
+		OpenStdin:  true,
+	}); err != nil {
+		return err
+	}
+
+	ready := make(chan struct{})
+	go func() {
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return
+		}
+		buf := make([]byte, relayChunkSize)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				line := base64.StdEncoding.EncodeToString(buf[:n]) + "\n"
+				if err := stream.Send(&corepb.ExecuteWorkloadOptions{ReplCmd: []byte(line)}); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				// close stdin so the relay exits
+				_ = stream.Send(&corepb.ExecuteWorkloadOptions{ReplCmd: escapeCommand})
+				return
+			}
+		}
+	}()
+
+	// output before relayReady is dropped
+	pending := []byte{}
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		switch {
+		case msg.StdStreamType == corepb.StdStreamType_ERUERROR:
+			return fmt.Errorf("%s", msg.Data)
+		case len(msg.Data) > len(exitDataPrefix) && bytes.HasPrefix(msg.Data, []byte(exitDataPrefix)):
+			if code := strings.TrimSpace(string(msg.Data[len(exitDataPrefix):])); code != "0" {
+				logrus.Warnf("[PortForward] Relay for %s exited with %s, is socat or nc in workload?", conn.RemoteAddr(), code)
+			}
+			return nil
+		case pending != nil:
+			rest, ok := cutReady(append(pending, msg.Data...))
+			if !ok {
+				pending = rest
+				continue
+			}
+			pending = nil
+			close(ready)
+			if _, err := conn.Write(rest); err != nil {
+				return err
+			}
+		default:
+			if _, err := conn.Write(msg.Data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// cutReady returns output after relayReady if it's found,
+// otherwise the tail which may be the beginning of relayReady
+func cutReady(output []byte) ([]byte, bool) {
+	if i := bytes.Index(output, []byte(relayReady)); i >= 0 {
+		return output[i+len(relayReady):], true
+	}
+	if len(output) > len(relayReady) {
+		output = output[len(output)-len(relayReady):]
+	}
+	return output, false
+}
+
+// parsePortPairs parses LOCAL_PORT:WORKLOAD_PORT, or PORT for the same port
+func parsePortPairs(args []string) ([][2]int, error) {
+	pairs := [][2]int{}
+	for _, arg := range args {
+		local, remote, ok := strings.Cut(arg, ":")
+		if !ok {
+			remote = local
+		}
+		l, err := strconv.Atoi(local)
+		if err != nil || l < 0 || l > 65535 {
+			return nil, fmt.Errorf("[PortForward] invalid local port in %s", arg)
+		}
+		r, err := strconv.Atoi(remote)
+		if err != nil || r <= 0 || r > 65535 {
+			return nil, fmt.Errorf("[PortForward] invalid workload port in %s", arg)
+		}
+		pairs = append(pairs, [2]int{l, r})
+	}
+	return pairs, nil
+}
+
+func cmdWorkloadPortForward(c *cli.Context) error {
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return err
+	}
+
+	id := c.Args().First()
+	if id == "" {
+		return fmt.Errorf("Workload ID should not be empty")
+	}
+	if c.NArg() < 2 {
+		return fmt.Errorf("[PortForward] ports should not be empty")
+	}
+	ports, err := parsePortPairs(c.Args().Tail())
+	if err != nil {
+		return err
+	}
+
+	o := &portForwardOptions{
+		client:  client,
+		id:      id,
+		address: c.String("address"),
+		ports:   ports,
+	}
+	return o.run(c.Context)
+}
@@ -0,0 +1,45 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortPairs(t *testing.T) {
+	pairs, err := parsePortPairs([]string{"8080:80", "9000", "0:443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]int{{8080, 80}, {9000, 9000}, {0, 443}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("parsePortPairs() = %v, want %v", pairs, expected)
+	}
+
+	for _, arg := range []string{"", "a:80", "80:b", "8080:0", "70000:80", "80:70000", "-1:80"} {
+		if _, err := parsePortPairs([]string{arg}); err == nil {
+			t.Errorf("parsePortPairs(%q) should fail", arg)
+		}
+	}
+}
+
+func TestCutReady(t *testing.T) {
+	// the marker may be split into messages
+	output := []byte("stty: noise\r\n" + relayReady + "data")
+	for split := 0; split <= len(output); split++ {
+		pending, ok := cutReady(append([]byte{}, output[:split]...))
+		if ok {
+			if string(pending)+string(output[split:]) != "data" {
+				t.Errorf("split at %d: rest = %q", split, pending)
+			}
+			continue
+		}
+		rest, ok := cutReady(append(pending, output[split:]...))
+		if !ok || string(rest) != "data" {
+			t.Errorf("split at %d: rest = %q, found = %v", split, rest, ok)
+		}
+	}
+
+	if _, ok := cutReady([]byte("no marker")); ok {
+		t.Error("cutReady() should not find marker")
+	}
+}
@@ -0,0 +1,121 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// probe checks the workload locally,
+// running means the workload answered, healthy means the answer is good
+type probe interface {
+	check(ctx context.Context) (running, healthy bool, err error)
+	String() string
+}
+
+type httpProbe struct {
+	url string
+}
+
+// check is healthy for 2xx and 3xx responses, any response means running
+func (p *httpProbe) check(ctx context.Context) (bool, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return false, false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return true, false, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return true, true, nil
+}
+
+func (p *httpProbe) String() string {
+	return p.url
+}
+
+type tcpProbe struct {
+	address string
+}
+
+func (p *tcpProbe) check(ctx context.Context) (bool, bool, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return false, false, err
+	}
+	conn.Close()
+	return true, true, nil
+}
+
+func (p *tcpProbe) String() string {
+	return "tcp:" + p.address
+}
+
+type execProbe struct {
+	command string
+}
+
+// check runs command by sh, exit code 0 means both running and healthy
+func (p *execProbe) check(ctx context.Context) (bool, bool, error) {
+	out, err := exec.CommandContext(ctx, "sh", "-c", p.command).CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(out)); out != "" {
+			return false, false, fmt.Errorf("%v: %s", err, out)
+		}
+		return false, false, err
+	}
+	return true, true, nil
+}
+
+func (p *execProbe) String() string {
+	return "exec:" + p.command
+}
+
+// parseProbe parses http://..., https://..., tcp:host:port and exec:command
+func parseProbe(s string) (probe, error) {
+	switch {
+	case strings.HasPrefix(s, "http://"), strings.HasPrefix(s, "https://"):
+		return &httpProbe{url: s}, nil
+	case strings.HasPrefix(s, "tcp:"):
+		address := strings.TrimPrefix(s, "tcp:")
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("invalid tcp probe %s: %v", s, err)
+		}
+		return &tcpProbe{address: address}, nil
+	case strings.HasPrefix(s, "exec:"):
+		command := strings.TrimPrefix(s, "exec:")
+		if command == "" {
+			return nil, errors.New("empty exec probe")
+		}
+		return &execProbe{command: command}, nil
+	default:
+		return nil, fmt.Errorf("invalid probe %s, should be http://..., https://..., tcp:host:port or exec:command", s)
+	}
+}
+
+// runProbes checks all probes, running if any of them is running,
+// healthy only if all of them are healthy
+func runProbes(ctx context.Context, probes []probe, timeout time.Duration) (running, healthy bool, errs []error) {
+	healthy = true
+	for _, p := range probes {
+		pctx, cancel := context.WithTimeout(ctx, timeout)
+		r, h, err := p.check(pctx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("probe %s failed: %v", p, err))
+		}
+		running = running || r
+		healthy = healthy && h
+	}
+	return running, healthy, errs
+}
@@ -0,0 +1,73 @@
+package workload
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseProbe(t *testing.T) {
+	cases := []struct {
+		probe    string
+		expected string
+		ok       bool
+	}{
+		{"http://127.0.0.1:8080/healthz", "http://127.0.0.1:8080/healthz", true},
+		{"https://example.com", "https://example.com", true},
+		{"tcp:127.0.0.1:6379", "tcp:127.0.0.1:6379", true},
+		{"tcp:[::1]:6379", "tcp:[::1]:6379", true},
+		{"exec:test -f /tmp/ready", "exec:test -f /tmp/ready", true},
+		{"tcp:6379", "", false},
+		{"exec:", "", false},
+		{"ftp://example.com", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		p, err := parseProbe(c.probe)
+		if c.ok != (err == nil) {
+			t.Errorf("parseProbe(%q) error = %v", c.probe, err)
+			continue
+		}
+		if err == nil && p.String() != c.expected {
+			t.Errorf("parseProbe(%q) = %s, want %s", c.probe, p, c.expected)
+		}
+	}
+}
+
+func TestRunProbes(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().String()
+	l.Close()
+
+	cases := []struct {
+		name             string
+		probes           []probe
+		running, healthy bool
+		errs             int
+	}{
+		{"all good", []probe{&httpProbe{url: ok.URL}, &tcpProbe{address: ok.Listener.Addr().String()}, &execProbe{command: "true"}}, true, true, 0},
+		{"bad status is running but unhealthy", []probe{&httpProbe{url: bad.URL}}, true, false, 1},
+		{"one unhealthy", []probe{&httpProbe{url: ok.URL}, &execProbe{command: "false"}}, true, false, 1},
+		{"nothing answers", []probe{&tcpProbe{address: closed}, &execProbe{command: "exit 1"}}, false, false, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			running, healthy, errs := runProbes(context.Background(), c.probes, time.Second)
+			if running != c.running || healthy != c.healthy || len(errs) != c.errs {
+				t.Errorf("runProbes() = %v, %v, %v", running, healthy, errs)
+			}
+		})
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
 	corepb "github.com/projecteru2/core/rpc/gen"
 
+	"github.com/google/uuid"
 	"github.com/juju/errors"
 	resourcetypes "github.com/projecteru2/core/resource/types"
 	"github.com/sirupsen/logrus"
@@ -16,51 +20,228 @@ import (
 )
 
 type reallocWorkloadsOptions struct {
-	client corepb.CoreRPCClient
-	opts   *corepb.ReallocOptions
+	client   corepb.CoreRPCClient
+	ids      []string
+	selector *workloadSelector
+	// opts is shared by all workloads, with Id set for each
+	opts *corepb.ReallocOptions
+	// delta is the increments of resources, to predict resources after reallocating
+	delta       *types.WorkloadResources
+	cpuBind     bool
+	concurrency int
+	dryRun      bool
 }
 
 func (o *reallocWorkloadsOptions) run(ctx context.Context) error {
-	resp, err := o.client.ReallocResource(ctx, o.opts)
+	workloads, err := o.workloads(ctx)
 	if err != nil {
 		return err
 	}
+	if len(workloads) == 0 {
+		return errors.New("[Realloc] no workloads selected")
+	}
+
+	results := make([]*types.ReallocResult, len(workloads))
+	for i, w := range workloads {
+		results[i] = &types.ReallocResult{
+			ID:       w.Id,
+			Name:     w.Name,
+			Podname:  w.Podname,
+			Nodename: w.Nodename,
+			DryRun:   o.dryRun,
+		}
+		before, err := types.ParseWorkloadResources(w.Resources)
+		if err != nil {
+			results[i].Error = fmt.Sprintf("invalid resources %v", err)
+			continue
+		}
+		results[i].Before = before
+		results[i].After = before.Add(o.delta)
+	}
 
-	if resp.Error != "" {
-		logrus.Infof("[Realloc] Failed by %+v", resp.Error)
+	if o.dryRun {
+		o.checkCapacity(ctx, results)
 	} else {
-		logrus.Info("[Realloc] Success")
+		o.realloc(ctx, results)
+	}
+
+	describe.ReallocResults(results...)
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("[Realloc] %d of %d workload(s) failed", failed, len(results)), partialFailureExitCode)
 	}
 	return nil
 }
 
-func cmdWorkloadRealloc(c *cli.Context) error {
-	client, err := utils.NewCoreRPCClient(c)
+// workloads returns workloads by IDs, or by selectors
+func (o *reallocWorkloadsOptions) workloads(ctx context.Context) ([]*corepb.Workload, error) {
+	if o.selector != nil {
+		return o.selector.list(ctx, o.client)
+	}
+	resp, err := o.client.GetWorkloads(ctx, &corepb.WorkloadIDs{IDs: o.ids})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return resp.Workloads, nil
+}
+
+// realloc reallocates workloads at most o.concurrency at the same time,
+// resources after reallocating are read back from eru-core
+func (o *reallocWorkloadsOptions) realloc(ctx context.Context, results []*types.ReallocResult) {
+	concurrency := o.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *types.ReallocResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			resp, err := o.client.ReallocResource(ctx, &corepb.ReallocOptions{
+				Id:        r.ID,
+				Resources: o.opts.Resources,
+			})
+			switch {
+			case err != nil:
+				r.Error = err.Error()
+			case resp.Error != "":
+				r.Error = resp.Error
+			}
+			if r.Error != "" {
+				logrus.Errorf("[Realloc] Failed to realloc %s: %s", r.Name, r.Error)
+				r.After = nil
+				return
+			}
+			r.Success = true
+
+			w, err := o.client.GetWorkload(ctx, &corepb.WorkloadID{Id: r.ID})
+			if err != nil {
+				logrus.Warnf("[Realloc] Failed to get resources of %s after realloc, show predicted ones: %v", r.Name, err)
+				return
+			}
+			if after, err := types.ParseWorkloadResources(w.Resources); err == nil {
+				r.After = after
+			}
+		}(r)
+	}
+	wg.Wait()
+}
+
+// checkCapacity checks if nodes have enough resources for increments of their workloads,
+// by capacity of pods, decrements always fit
+func (o *reallocWorkloadsOptions) checkCapacity(ctx context.Context, results []*types.ReallocResult) {
+	grow := &types.WorkloadResources{}
+	if o.delta.CPURequest > 0 {
+		grow.CPURequest = o.delta.CPURequest
+	}
+	if o.delta.MemoryRequest > 0 {
+		grow.MemoryRequest = o.delta.MemoryRequest
+	}
+	if o.delta.StorageRequest > 0 {
+		grow.StorageRequest = o.delta.StorageRequest
+	}
+	for _, r := range results {
+		r.Success = r.Error == ""
+	}
+	if grow.CPURequest == 0 && grow.MemoryRequest == 0 && grow.StorageRequest == 0 {
+		return
 	}
 
-	opts, err := generateReallocOptions(c)
+	// binding needs cpu, growing of memory or storage only is checked without binding
+	grow.CPUBind = o.cpuBind && grow.CPURequest > 0
+
+	// pod -> node -> how many workloads grow on it
+	needs := map[string]map[string]int{}
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		if needs[r.Podname] == nil {
+			needs[r.Podname] = map[string]int{}
+		}
+		needs[r.Podname][r.Nodename]++
+	}
+
+	for podname, nodes := range needs {
+		nodenames := []string{}
+		for nodename := range nodes {
+			nodenames = append(nodenames, nodename)
+		}
+		resp, err := o.client.CalculateCapacity(ctx, &corepb.DeployOptions{
+			Resources: grow.ToDeployResources(),
+			Entrypoint: &corepb.EntrypointOptions{
+				Name: uuid.New().String(),
+			},
+			DeployStrategy: corepb.DeployOptions_DUMMY,
+			Podname:        podname,
+			NodeFilter: &corepb.NodeFilter{
+				Includes: nodenames,
+			},
+		})
+		for _, r := range results {
+			if r.Error != "" || r.Podname != podname {
+				continue
+			}
+			switch capacity, need := resp.GetNodeCapacities()[r.Nodename], nodes[r.Nodename]; {
+			case err != nil:
+				r.Error = fmt.Sprintf("calculate capacity failed %v", err)
+			case capacity < int64(need):
+				r.Error = fmt.Sprintf("not enough resources on node %s, capacity %d for %d workload(s)", r.Nodename, capacity, need)
+			}
+			r.Success = r.Error == ""
+		}
+	}
+}
+
+func cmdWorkloadRealloc(c *cli.Context) error {
+	client, err := utils.NewCoreRPCClient(c)
 	if err != nil {
 		return err
 	}
 
 	o := &reallocWorkloadsOptions{
-		client: client,
-		opts:   opts,
+		client:      client,
+		cpuBind:     c.Bool("cpu-bind"),
+		concurrency: c.Int("concurrency"),
+		dryRun:      c.Bool("dry-run"),
+	}
+	switch {
+	case isSelecting(c):
+		if c.NArg() > 0 {
+			return errors.New("Workload IDs can't be given with --app/--entry/--node/--label")
+		}
+		o.selector = newWorkloadSelector(c)
+	case c.NArg() > 0:
+		o.ids = c.Args().Slice()
+	default:
+		return errors.New("Workload ID must be given")
 	}
-	return o.run(c.Context)
-}
 
-func generateReallocOptions(c *cli.Context) (*corepb.ReallocOptions, error) {
-	id := c.Args().First()
-	if id == "" {
-		return nil, errors.New("Workload ID must be given")
+	if o.opts, o.delta, err = generateReallocOptions(c); err != nil {
+		return err
 	}
+	return o.run(c.Context)
+}
 
+// generateReallocOptions returns options without workload ID,
+// and the increments of resources
+func generateReallocOptions(c *cli.Context) (*corepb.ReallocOptions, *types.WorkloadResources, error) {
 	memoryRequest, memoryLimit, err := memoryOption(c)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var volumesRequest, volumesLimit []string
@@ -74,7 +255,7 @@ func generateReallocOptions(c *cli.Context) (*corepb.ReallocOptions, error) {
 	bindCPU := c.Bool("cpu-bind")
 	unbindCPU := c.Bool("cpu-unbind")
 	if bindCPU && unbindCPU {
-		return nil, errors.New("cpu-bind and cpu-unbind can not both be set")
+		return nil, nil, errors.New("cpu-bind and cpu-unbind can not both be set")
 	}
 	bindCPUOpt := corepb.TriOpt_KEEP
 	if bindCPU {
@@ -86,7 +267,7 @@ func generateReallocOptions(c *cli.Context) (*corepb.ReallocOptions, error) {
 
 	storageRequest, storageLimit, err := storageOption(c)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	cpuRequest, cpuLimit := cpuOption(c)
@@ -128,11 +309,16 @@ func generateReallocOptions(c *cli.Context) (*corepb.ReallocOptions, error) {
 			resources[k] = eb
 		}
 	} else {
-		return nil, fmt.Errorf("[generateReallocOptions] get extra resources failed %v", err)
+		return nil, nil, fmt.Errorf("[generateReallocOptions] get extra resources failed %v", err)
 	}
 
-	return &corepb.ReallocOptions{
-		Id:        id,
-		Resources: resources,
-	}, nil
+	delta := &types.WorkloadResources{
+		CPURequest:     cpuRequest,
+		CPULimit:       cpuLimit,
+		MemoryRequest:  memoryRequest,
+		MemoryLimit:    memoryLimit,
+		StorageRequest: storageRequest,
+		StorageLimit:   storageLimit,
+	}
+	return &corepb.ReallocOptions{Resources: resources}, delta, nil
 }
@@ -0,0 +1,65 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"google.golang.org/grpc"
+)
+
+type capacityClient struct {
+	corepb.CoreRPCClient
+	resources map[string]map[string]any
+	capacity  int64
+}
+
+func (c *capacityClient) CalculateCapacity(_ context.Context, in *corepb.DeployOptions, _ ...grpc.CallOption) (*corepb.CapacityMessage, error) {
+	c.resources = map[string]map[string]any{}
+	for k, v := range in.Resources {
+		params := map[string]any{}
+		if err := json.Unmarshal(v, &params); err != nil {
+			return nil, err
+		}
+		c.resources[k] = params
+	}
+	capacities := map[string]int64{}
+	for _, nodename := range in.NodeFilter.Includes {
+		capacities[nodename] = c.capacity
+	}
+	return &corepb.CapacityMessage{NodeCapacities: capacities}, nil
+}
+
+func TestReallocCheckCapacity(t *testing.T) {
+	client := &capacityClient{capacity: 1}
+	o := &reallocWorkloadsOptions{
+		client:  client,
+		delta:   &types.WorkloadResources{CPURequest: 0.5, MemoryRequest: -1024, StorageRequest: 2048},
+		cpuBind: true,
+	}
+	results := []*types.ReallocResult{
+		{ID: "a", Podname: "muroq", Nodename: "test0"},
+		{ID: "b", Podname: "muroq", Nodename: "test1"},
+		{ID: "c", Podname: "muroq", Nodename: "test1"},
+	}
+	o.checkCapacity(context.Background(), results)
+
+	// requests are sent with the keys resource plugins read, decrements are not counted
+	cpumem, storage := client.resources["cpumem"], client.resources["storage"]
+	if cpumem["cpu-request"] != 0.5 || cpumem["memory-request"] != 0.0 || cpumem["cpu-bind"] != true || storage["storage-request"] != 2048.0 {
+		t.Errorf("resources = %v", client.resources)
+	}
+	if !results[0].Success || results[1].Success || results[2].Success {
+		t.Errorf("test0 fits 1 workload, test1 doesn't fit 2, got %v %v %v", results[0].Error, results[1].Error, results[2].Error)
+	}
+
+	// binding needs cpu
+	o.delta = &types.WorkloadResources{MemoryRequest: 1024}
+	o.checkCapacity(context.Background(), results[:1])
+	if _, ok := client.resources["cpumem"]["cpu-bind"]; ok || client.resources["cpumem"]["memory-request"] != 1024.0 {
+		t.Errorf("resources = %v", client.resources)
+	}
+}
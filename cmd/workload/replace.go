@@ -2,18 +2,20 @@ package workload
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/projecteru2/cli/cmd/utils"
 	"github.com/projecteru2/cli/types"
+	resourcetypes "github.com/projecteru2/core/resource/types"
 	corepb "github.com/projecteru2/core/rpc/gen"
 
+	"github.com/juju/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
-	"gopkg.in/yaml.v2"
 )
 
 type replaceWorkloadsOptions struct {
@@ -22,10 +24,86 @@ type replaceWorkloadsOptions struct {
 	labels         map[string]string
 	copys          map[string]string
 	networkInherit bool
+	resources      *replaceResources
+	wait           bool
+	waitTimeout    time.Duration
+	reportFile     string
 }
 
 func (o *replaceWorkloadsOptions) run(ctx context.Context) error {
-	return doReplaceWorkload(ctx, o.client, o.opts, o.networkInherit, o.labels, o.copys)
+	opts := &corepb.ReplaceOptions{
+		DeployOpt:      o.opts,
+		Networkinherit: o.networkInherit,
+		FilterLabels:   o.labels,
+		Copy:           o.copys,
+	}
+	msgs, err := doReplaceWorkload(ctx, o.client, opts, "")
+	report := &types.DeployReport{Workloads: []*types.WorkloadReport{}}
+	for _, msg := range msgs {
+		report.Workloads = append(report.Workloads, replaceReport(o.opts.Entrypoint.Name, msg))
+	}
+	o.resizeAll(ctx, msgs, report.Workloads)
+	if err := showReport(report, o.reportFile); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.wait {
+		ids := []string{}
+		for _, w := range report.Workloads {
+			if w.Success {
+				ids = append(ids, w.ID)
+			}
+		}
+		if err := waitHealthy(ctx, o.client, ids, o.waitTimeout); err != nil {
+			return err
+		}
+	}
+	return reportFailure("Replace", report)
+}
+
+// resizeAll reallocs replaced workloads to the given resources,
+// it's not atomic with replacing, old workloads are already removed when resizing,
+// so workloads failed to resize are marked failed in reports, running with resources of old workloads
+func (o *replaceWorkloadsOptions) resizeAll(ctx context.Context, msgs []*corepb.ReplaceWorkloadMessage, reports []*types.WorkloadReport) {
+	if o.resources.empty() {
+		return
+	}
+
+	// new workloads inherit resources of old ones,
+	// so realloc them by the difference to the given resources
+	for i, msg := range msgs {
+		if !reports[i].Success {
+			continue
+		}
+		if err := o.resize(ctx, msg.Create); err != nil {
+			logrus.Errorf("[Replace] Resize workload %s failed %v", msg.Create.Id, err)
+			reports[i].Success = false
+			reports[i].Error = fmt.Sprintf("replaced, but resize failed %v, running with resources of the old workload", err)
+		}
+	}
+}
+
+func (o *replaceWorkloadsOptions) resize(ctx context.Context, createMsg *corepb.CreateWorkloadMessage) error {
+	current, err := types.ParseWorkloadResources(createMsg.Resources)
+	if err != nil {
+		return err
+	}
+	opts := o.resources.reallocOptions(createMsg.Id, current)
+	if opts == nil {
+		return nil
+	}
+	resp, err := o.client.ReallocResource(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	logrus.Infof("[Replace] Workload %s resized", createMsg.Id)
+	return nil
 }
 
 func cmdWorkloadReplace(c *cli.Context) error {
@@ -43,7 +121,21 @@ func cmdWorkloadReplace(c *cli.Context) error {
 		return fmt.Errorf("[Replace] entry can not contain _")
 	}
 
-	opts, err := generateReplaceOptions(c)
+	specs, err := loadSpecs(c)
+	if err != nil {
+		return err
+	}
+	opts, err := generateReplaceOptions(c, specs)
+	if err != nil {
+		return err
+	}
+	revision, err := newRevision(specs, opts.Image)
+	if err != nil {
+		return fmt.Errorf("[Replace] generate revision failed %v", err)
+	}
+	stampRevision(opts, revision)
+
+	resources, err := generateReplaceResources(c)
 	if err != nil {
 		return err
 	}
@@ -59,28 +151,43 @@ func cmdWorkloadReplace(c *cli.Context) error {
 		copys:          utils.SplitFiles(c.StringSlice("copy")),
 		labels:         utils.SplitEquality(c.StringSlice("label")),
 		networkInherit: networkInherit,
+		resources:      resources,
+		wait:           c.Bool("wait"),
+		waitTimeout:    c.Duration("wait-timeout"),
+		reportFile:     c.String("report"),
 	}
 	return o.run(c.Context)
 }
 
-func doReplaceWorkload(ctx context.Context, client corepb.CoreRPCClient, deployOpts *corepb.DeployOptions, networkInherit bool, labels map[string]string, copys map[string]string) error {
-	opts := &corepb.ReplaceOptions{
-		DeployOpt:      deployOpts,
-		Networkinherit: networkInherit,
-		FilterLabels:   labels,
-		Copy:           copys,
+// doReplaceWorkload replaces workloads and records them in a journal,
+// rollbackOf is the journal rolled back by this replace, if it is
+func doReplaceWorkload(ctx context.Context, client corepb.CoreRPCClient, opts *corepb.ReplaceOptions, rollbackOf string) ([]*corepb.ReplaceWorkloadMessage, error) {
+	journal, err := newJournal(ctx, client, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[Replace] snapshot workloads failed %v", err)
+	}
+	journal.RollbackOf = rollbackOf
+	if err := saveJournal(journal); err != nil {
+		return nil, fmt.Errorf("[Replace] save journal failed %v", err)
 	}
+	logrus.Infof("[Replace] Journal %s, roll back by `workload rollback %s`", journal.ID, journal.ID)
+
 	resp, err := client.ReplaceWorkload(ctx, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	msgs := []*corepb.ReplaceWorkloadMessage{}
 	for {
 		msg, err := resp.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return err
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+		if err := recordJournal(journal, msg); err != nil {
+			logrus.Warnf("[Replace] Record journal %s failed %v", journal.ID, err)
 		}
 
 		logrus.Infof("[Replace] Replace %s", msg.Remove.Id)
@@ -108,34 +215,10 @@ func doReplaceWorkload(ctx context.Context, client corepb.CoreRPCClient, deployO
 			logrus.Infof("[Replace] Bound %s ip %s", name, publish)
 		}
 	}
-	return nil
+	return msgs, nil
 }
 
-func generateReplaceOptions(c *cli.Context) (*corepb.DeployOptions, error) {
-	specURI := c.Args().First()
-	if specURI == "" {
-		return nil, fmt.Errorf("a specs must be given")
-	}
-	logrus.Debugf("[Replace] Replace with %s", specURI)
-
-	var (
-		data []byte
-		err  error
-	)
-	if strings.HasPrefix(specURI, "http") {
-		data, err = utils.GetSpecFromRemote(specURI)
-	} else {
-		data, err = ioutil.ReadFile(specURI)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	specs := &types.Specs{}
-	if err := yaml.Unmarshal(data, specs); err != nil {
-		return nil, fmt.Errorf("[generateReplaceOptions] get specs failed %v", err)
-	}
-
+func generateReplaceOptions(c *cli.Context, specs *types.Specs) (*corepb.DeployOptions, error) {
 	entry := c.String("entry")
 
 	network := c.String("network")
@@ -188,6 +271,7 @@ func generateReplaceOptions(c *cli.Context) (*corepb.DeployOptions, error) {
 			Restart:     entrypoint.Restart,
 			Sysctls:     entrypoint.Sysctls,
 		},
+		// core copies resources of old workloads, resizing is done by realloc after replacing
 		Resources: nil,
 		Podname:   c.String("pod"),
 		NodeFilter: &corepb.NodeFilter{
@@ -213,3 +297,127 @@ func generateReplaceOptions(c *cli.Context) (*corepb.DeployOptions, error) {
 		RawArgs:        []byte{},
 	}, nil
 }
+
+// replaceResources holds resources given by flags,
+// nil means not given and inherited from old workloads
+type replaceResources struct {
+	cpuRequest     *float64
+	cpuLimit       *float64
+	memoryRequest  *int64
+	memoryLimit    *int64
+	storageRequest *int64
+	storageLimit   *int64
+	extra          map[string][]byte
+}
+
+func generateReplaceResources(c *cli.Context) (*replaceResources, error) {
+	r := &replaceResources{}
+	float := func(name string) *float64 {
+		v := c.Float64(name)
+		return &v
+	}
+	size := func(name string) (*int64, error) {
+		v, err := utils.ParseRAMInHuman(c.String(name))
+		return &v, err
+	}
+
+	var err error
+	if c.IsSet("cpu-request") {
+		r.cpuRequest = float("cpu-request")
+	}
+	if c.IsSet("cpu-limit") {
+		r.cpuLimit = float("cpu-limit")
+	}
+	if c.IsSet("cpu") {
+		r.cpuRequest, r.cpuLimit = float("cpu"), float("cpu")
+	}
+	for _, f := range []struct {
+		name string
+		v    **int64
+	}{
+		{"memory-request", &r.memoryRequest},
+		{"memory-limit", &r.memoryLimit},
+		{"memory", &r.memoryRequest},
+		{"memory", &r.memoryLimit},
+		{"storage-request", &r.storageRequest},
+		{"storage-limit", &r.storageLimit},
+		{"storage", &r.storageRequest},
+		{"storage", &r.storageLimit},
+	} {
+		if !c.IsSet(f.name) {
+			continue
+		}
+		if *f.v, err = size(f.name); err != nil {
+			return nil, fmt.Errorf("[generateReplaceResources] parse %s failed %v", f.name, err)
+		}
+	}
+
+	// plugins of extra resources are unknown to cli, current values can't be read to compute differences,
+	// so they're taken as increments, and named so to tell them from resources above
+	extraResources := map[string]any{}
+	if v := c.String("extra-resource-increments"); v != "" {
+		if err := json.Unmarshal([]byte(v), &extraResources); err != nil {
+			return nil, fmt.Errorf("[generateReplaceResources] parse extra-resource-increments failed %v", err)
+		}
+	}
+	for k, v := range extraResources {
+		if k == "cpumem" || k == "storage" {
+			continue
+		}
+		if r.extra == nil {
+			r.extra = map[string][]byte{}
+		}
+		r.extra[k], _ = json.Marshal(v)
+	}
+	return r, nil
+}
+
+func (r *replaceResources) empty() bool {
+	return r == nil || (r.cpuRequest == nil && r.cpuLimit == nil &&
+		r.memoryRequest == nil && r.memoryLimit == nil &&
+		r.storageRequest == nil && r.storageLimit == nil && len(r.extra) == 0)
+}
+
+// reallocOptions returns realloc options changing current resources to the given ones,
+// realloc takes increments, so they're the differences, nil if nothing to change.
+// extra resource increments are passed as they are, same as `workload realloc`
+func (r *replaceResources) reallocOptions(id string, current *types.WorkloadResources) *corepb.ReallocOptions {
+	cpumem := resourcetypes.RawParams{"keep-cpu-bind": true}
+	storage := resourcetypes.RawParams{}
+	changed := false
+	if r.cpuRequest != nil && *r.cpuRequest != current.CPURequest {
+		cpumem["cpu-request"], changed = *r.cpuRequest-current.CPURequest, true
+	}
+	if r.cpuLimit != nil && *r.cpuLimit != current.CPULimit {
+		cpumem["cpu-limit"], changed = *r.cpuLimit-current.CPULimit, true
+	}
+	if r.memoryRequest != nil && *r.memoryRequest != current.MemoryRequest {
+		cpumem["memory-request"], changed = *r.memoryRequest-current.MemoryRequest, true
+	}
+	if r.memoryLimit != nil && *r.memoryLimit != current.MemoryLimit {
+		cpumem["memory-limit"], changed = *r.memoryLimit-current.MemoryLimit, true
+	}
+	if r.storageRequest != nil && *r.storageRequest != current.StorageRequest {
+		storage["storage-request"], changed = *r.storageRequest-current.StorageRequest, true
+	}
+	if r.storageLimit != nil && *r.storageLimit != current.StorageLimit {
+		storage["storage-limit"], changed = *r.storageLimit-current.StorageLimit, true
+	}
+	if !changed && len(r.extra) == 0 {
+		return nil
+	}
+
+	cb, _ := json.Marshal(cpumem)
+	sb, _ := json.Marshal(storage)
+	resources := map[string][]byte{
+		"cpumem":  cb,
+		"storage": sb,
+	}
+	for k, v := range r.extra {
+		resources[k] = v
+	}
+	return &corepb.ReallocOptions{
+		Id:        id,
+		Resources: resources,
+	}
+}
@@ -0,0 +1,99 @@
+package workload
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+)
+
+// partialFailureExitCode is the exit code when some of the workloads failed,
+// so scripts can tell it from errors like invalid arguments
+const partialFailureExitCode = 2
+
+// workloadReports converts messages of creating or replacing workloads into reports
+func workloadReports(entry string, msgs []any) []*types.WorkloadReport {
+	reports := []*types.WorkloadReport{}
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case *corepb.CreateWorkloadMessage:
+			reports = append(reports, createReport(entry, m))
+		case *corepb.ReplaceWorkloadMessage:
+			reports = append(reports, replaceReport(entry, m))
+		}
+	}
+	return reports
+}
+
+func createReport(entry string, msg *corepb.CreateWorkloadMessage) *types.WorkloadReport {
+	return &types.WorkloadReport{
+		Entrypoint: entry,
+		Action:     "create",
+		ID:         msg.Id,
+		Name:       msg.Name,
+		Nodename:   msg.Nodename,
+		Publish:    msg.Publish,
+		Hook:       string(msg.Hook),
+		Success:    msg.Success,
+		Error:      msg.Error,
+	}
+}
+
+func replaceReport(entry string, msg *corepb.ReplaceWorkloadMessage) *types.WorkloadReport {
+	report := &types.WorkloadReport{Entrypoint: entry, Action: "replace", Error: msg.Error}
+	if msg.Create != nil {
+		report = createReport(entry, msg.Create)
+		report.Action = "replace"
+		if msg.Error != "" {
+			report.Error = msg.Error
+		}
+	}
+	if msg.Remove != nil {
+		report.ReplacedID = msg.Remove.Id
+		report.StopHook = msg.Remove.Hook
+	}
+	report.Success = msg.Error == "" && msg.Create != nil && msg.Create.Success
+	return report
+}
+
+// showReport describes the report and writes it to file if given,
+// the file is in yaml if it ends with .yaml or .yml, otherwise in json
+func showReport(report *types.DeployReport, file string) error {
+	describe.DeployReport(report)
+	if file == "" {
+		return nil
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		b, err = yaml.Marshal(report)
+	default:
+		b, err = json.MarshalIndent(report, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, b, 0600); err != nil {
+		return fmt.Errorf("write report to %s failed %v", file, err)
+	}
+	return nil
+}
+
+// reportFailure returns an error exiting with partialFailureExitCode if any workload failed
+func reportFailure(tag string, report *types.DeployReport) error {
+	if failed := report.Failed(); failed > 0 {
+		return cli.Exit(fmt.Sprintf("[%s] %d of %d workload(s) failed", tag, failed, len(report.Workloads)), partialFailureExitCode)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package workload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/user"
+	"sort"
+	"time"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/types"
+	"github.com/projecteru2/cli/version"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coretypes "github.com/projecteru2/core/types"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// newRevision returns revision labels of this deploy or replace,
+// all workloads created by one command share the same revision
+func newRevision(specs *types.Specs, image string) (map[string]string, error) {
+	b, err := yaml.Marshal(specs)
+	if err != nil {
+		return nil, err
+	}
+	specSum := sha256.Sum256(b)
+	specHash := hex.EncodeToString(specSum[:])[:12]
+
+	now := time.Now()
+	revSum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%d", image, specHash, now.UnixNano())))
+
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	return map[string]string{
+		types.LabelRevision:   hex.EncodeToString(revSum[:])[:12],
+		types.LabelSpecHash:   specHash,
+		types.LabelDeployer:   fmt.Sprintf("%s@%s", username, utils.GetHostname()),
+		types.LabelDeployedAt: now.UTC().Format(time.RFC3339),
+		types.LabelCLIVersion: version.VERSION,
+	}, nil
+}
+
+// stampRevision sets revision labels and the entrypoint label to deploy options,
+// reserved labels given in specs are overridden
+func stampRevision(opts *corepb.DeployOptions, revision map[string]string) {
+	labels := map[string]string{}
+	for k, v := range opts.Labels {
+		if types.IsReservedLabel(k) {
+			logrus.Warnf("[Revision] Label %s is reserved, %s in specs is ignored", k, v)
+			continue
+		}
+		labels[k] = v
+	}
+	for k, v := range revision {
+		labels[k] = v
+	}
+	labels[types.LabelEntrypoint] = entrypointLabel(opts)
+	opts.Labels = labels
+}
+
+// entrypointLabel returns entrypoint options in json,
+// which are not carried by workloads, so that specs can be exported back
+func entrypointLabel(opts *corepb.DeployOptions) string {
+	label := &types.EntrypointLabel{
+		DNS:        opts.Dns,
+		ExtraHosts: opts.ExtraHosts,
+		User:       opts.User,
+	}
+	for name := range opts.Data {
+		label.Files = append(label.Files, name)
+	}
+	sort.Strings(label.Files)
+	if entry := opts.Entrypoint; entry != nil {
+		label.Commands = entry.Commands
+		label.Dir = entry.Dir
+		label.Restart = entry.Restart
+		label.Sysctls = entry.Sysctls
+		if entry.Hook != nil {
+			label.Hook = &coretypes.Hook{
+				AfterStart: entry.Hook.AfterStart,
+				BeforeStop: entry.Hook.BeforeStop,
+				Force:      entry.Hook.Force,
+			}
+		}
+		if entry.Log != nil {
+			label.Log = &coretypes.LogConfig{
+				Type:   entry.Log.Type,
+				Config: entry.Log.Config,
+			}
+		}
+	}
+	b, _ := json.Marshal(label)
+	return string(b)
+}
@@ -0,0 +1,181 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	"github.com/projecteru2/cli/describe"
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"github.com/getlantern/deepcopy"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+type rollbackWorkloadsOptions struct {
+	client    corepb.CoreRPCClient
+	journalID string
+}
+
+func (o *rollbackWorkloadsOptions) run(ctx context.Context) error {
+	if o.journalID == "" {
+		journals, err := listJournals()
+		if err != nil {
+			return fmt.Errorf("[Rollback] list journals failed %v", err)
+		}
+		describe.Journals(journals...)
+		return nil
+	}
+
+	journal, err := loadJournal(o.journalID)
+	if err != nil {
+		return fmt.Errorf("[Rollback] load journal %s failed %v", o.journalID, err)
+	}
+	entries := journal.Replaced()
+	if len(entries) == 0 {
+		return fmt.Errorf("[Rollback] no workloads replaced in journal %s", journal.ID)
+	}
+
+	// old workloads may run different revisions, images or env,
+	// replace new workloads of the same ones together
+	groups := map[string][]*types.JournalEntry{}
+	keys := []string{}
+	for _, entry := range entries {
+		key := entry.Labels[types.LabelRevision] + "\n" + entry.Image + "\n" + strings.Join(entry.Env, "\n")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+	sort.Strings(keys)
+
+	// options of all groups are built before replacing anything, nothing is replaced if any group can't be rolled back
+	allDeployOpts := map[string]*corepb.DeployOptions{}
+	for _, key := range keys {
+		deployOpts, err := rollbackDeployOptions(journal.Options.DeployOpt, groups[key][0])
+		if err != nil {
+			return fmt.Errorf("[Rollback] %v", err)
+		}
+		allDeployOpts[key] = deployOpts
+	}
+
+	failed := 0
+	for _, key := range keys {
+		group := groups[key]
+		deployOpts := allDeployOpts[key]
+
+		byNewID := map[string]*types.JournalEntry{}
+		ids := []string{}
+		for _, entry := range group {
+			ids = append(ids, entry.NewID)
+			byNewID[entry.NewID] = entry
+		}
+		logrus.Infof("[Rollback] Roll back %d workload(s) to %s", len(ids), deployOpts.Image)
+
+		msgs, err := doReplaceWorkload(ctx, o.client, &corepb.ReplaceOptions{
+			DeployOpt:      deployOpts,
+			Networkinherit: true,
+			Copy:           journal.Options.Copy,
+			IDs:            ids,
+		}, journal.ID)
+		if err != nil {
+			return fmt.Errorf("[Rollback] replace failed %v", err)
+		}
+		for _, msg := range msgs {
+			if msg.Error != "" || msg.Create == nil || !msg.Create.Success {
+				failed++
+				continue
+			}
+			// resources may be changed by the replace, restore them
+			if err := o.restoreResources(ctx, msg.Create, byNewID[msg.Remove.Id]); err != nil {
+				logrus.Errorf("[Rollback] Restore resources of %s failed %v", msg.Create.Id, err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("[Rollback] %d workload(s) not rolled back", failed)
+	}
+	return nil
+}
+
+// rollbackDeployOptions returns options to replace new workloads back to the old one recorded in entry,
+// entrypoint options, image, env and revision labels are from the old one, the rest are from the replace,
+// files shipped by the replace are not shipped again
+func rollbackDeployOptions(replaceOpts *corepb.DeployOptions, entry *types.JournalEntry) (*corepb.DeployOptions, error) {
+	old := &corepb.Workload{
+		Name:       entry.OldName,
+		Privileged: entry.Privileged,
+		Labels:     entry.Labels,
+	}
+	entrypoint, label, err := utils.RedeployEntrypoint(old)
+	if err != nil {
+		return nil, fmt.Errorf("can't roll back to %s: %v", entry.OldName, err)
+	}
+
+	deployOpts := &corepb.DeployOptions{}
+	if err := deepcopy.Copy(deployOpts, replaceOpts); err != nil {
+		return nil, fmt.Errorf("copy options failed %v", err)
+	}
+	deployOpts.Entrypoint = entrypoint
+	deployOpts.Dns = label.DNS
+	deployOpts.ExtraHosts = label.ExtraHosts
+	deployOpts.User = label.User
+	deployOpts.Image = entry.Image
+	deployOpts.Env = utils.DeployedEnv(entry.Env)
+	deployOpts.Data = nil
+
+	// back to the revision of old workloads
+	labels := map[string]string{}
+	for k, v := range deployOpts.Labels {
+		if !types.IsReservedLabel(k) {
+			labels[k] = v
+		}
+	}
+	for k, v := range entry.Labels {
+		if types.IsReservedLabel(k) {
+			labels[k] = v
+		}
+	}
+	deployOpts.Labels = labels
+	return deployOpts, nil
+}
+
+func (o *rollbackWorkloadsOptions) restoreResources(ctx context.Context, createMsg *corepb.CreateWorkloadMessage, entry *types.JournalEntry) error {
+	if entry == nil || entry.Resources == "" {
+		return nil
+	}
+	old, err := types.ParseWorkloadResources(entry.Resources)
+	if err != nil {
+		return err
+	}
+	r := &replaceWorkloadsOptions{
+		client: o.client,
+		resources: &replaceResources{
+			cpuRequest:     &old.CPURequest,
+			cpuLimit:       &old.CPULimit,
+			memoryRequest:  &old.MemoryRequest,
+			memoryLimit:    &old.MemoryLimit,
+			storageRequest: &old.StorageRequest,
+			storageLimit:   &old.StorageLimit,
+		},
+	}
+	return r.resize(ctx, createMsg)
+}
+
+func cmdWorkloadRollback(c *cli.Context) error {
+	client, err := utils.NewCoreRPCClient(c)
+	if err != nil {
+		return err
+	}
+
+	o := &rollbackWorkloadsOptions{
+		client:    client,
+		journalID: c.Args().First(),
+	}
+	return o.run(c.Context)
+}
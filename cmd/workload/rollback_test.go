@@ -0,0 +1,71 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+)
+
+func TestRollbackDeployOptions(t *testing.T) {
+	replaceOpts := &corepb.DeployOptions{
+		Name:    "test",
+		Podname: "muroq",
+		Image:   "python:3.11",
+		Entrypoint: &corepb.EntrypointOptions{
+			Name:     "http",
+			Commands: []string{"python3 -m http.server 9000"},
+			Hook:     &corepb.HookOptions{AfterStart: []string{"echo new"}},
+		},
+		Env:  []string{"VERSION=new"},
+		Data: map[string][]byte{"/etc/app.conf": []byte("new")},
+		Labels: map[string]string{
+			"owner":               "tonic",
+			types.LabelRevision:   "new",
+			types.LabelEntrypoint: `{"commands":["python3 -m http.server 9000"]}`,
+		},
+	}
+	entry := &types.JournalEntry{
+		OldName:    "test_http_RfKuXJ",
+		Privileged: true,
+		Image:      "python:3.10",
+		Env:        []string{"APP_NAME=test", "ERU_POD=muroq", "HOSTNAME=abc", "VERSION=old"},
+		Labels: map[string]string{
+			"ERU_META":            `{"Publish":["8000"]}`,
+			types.LabelRevision:   "old",
+			types.LabelEntrypoint: `{"commands":["python3 -m http.server"],"user":"nobody"}`,
+		},
+	}
+
+	opts, err := rollbackDeployOptions(replaceOpts, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Image != "python:3.10" || opts.Podname != "muroq" || opts.User != "nobody" || opts.Data != nil {
+		t.Errorf("rollbackDeployOptions() = %+v", opts)
+	}
+	if e := opts.Entrypoint; e.Name != "http" || !e.Privileged || e.Hook != nil ||
+		!reflect.DeepEqual(e.Commands, []string{"python3 -m http.server"}) || !reflect.DeepEqual(e.Publish, []string{"8000"}) {
+		t.Errorf("entrypoint is not the old one: %+v", e)
+	}
+	if !reflect.DeepEqual(opts.Env, []string{"VERSION=old"}) {
+		t.Errorf("env = %v", opts.Env)
+	}
+	expected := map[string]string{
+		"owner":               "tonic",
+		types.LabelRevision:   "old",
+		types.LabelEntrypoint: `{"commands":["python3 -m http.server"],"user":"nobody"}`,
+	}
+	if !reflect.DeepEqual(opts.Labels, expected) {
+		t.Errorf("labels = %v, want %v", opts.Labels, expected)
+	}
+	if replaceOpts.Image != "python:3.11" || replaceOpts.Entrypoint.Hook == nil {
+		t.Error("options of the replace are changed")
+	}
+
+	delete(entry.Labels, types.LabelEntrypoint)
+	if _, err := rollbackDeployOptions(replaceOpts, entry); err == nil {
+		t.Error("rollbackDeployOptions() should fail without entrypoint label")
+	}
+}
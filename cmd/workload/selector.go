@@ -0,0 +1,65 @@
+package workload
+
+import (
+	"context"
+	"io"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	corepb "github.com/projecteru2/core/rpc/gen"
+
+	"github.com/urfave/cli/v2"
+)
+
+// workloadSelector selects workloads by app, entry, nodes and labels,
+// from flags --app, --entry, --node and --label
+type workloadSelector struct {
+	appname    string
+	entrypoint string
+	nodenames  []string
+	labels     map[string]string
+}
+
+// isSelecting tells if any selector flag is given
+func isSelecting(c *cli.Context) bool {
+	return c.IsSet("app") || c.IsSet("entry") || c.IsSet("node") || c.IsSet("label")
+}
+
+func newWorkloadSelector(c *cli.Context) *workloadSelector {
+	return &workloadSelector{
+		appname:    c.String("app"),
+		entrypoint: c.String("entry"),
+		nodenames:  c.StringSlice("node"),
+		labels:     utils.SplitEquality(c.StringSlice("label")),
+	}
+}
+
+// list lists workloads by selectors, for each node if nodes are given
+func (s *workloadSelector) list(ctx context.Context, client corepb.CoreRPCClient) ([]*corepb.Workload, error) {
+	nodenames := s.nodenames
+	if len(nodenames) == 0 {
+		nodenames = []string{""}
+	}
+	workloads := []*corepb.Workload{}
+	for _, nodename := range nodenames {
+		resp, err := client.ListWorkloads(ctx, &corepb.ListWorkloadsOptions{
+			Appname:    s.appname,
+			Entrypoint: s.entrypoint,
+			Nodename:   nodename,
+			Labels:     s.labels,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for {
+			w, err := resp.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			workloads = append(workloads, w)
+		}
+	}
+	return workloads, nil
+}
@@ -1,88 +1,209 @@
 package workload
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"sync"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/projecteru2/cli/cmd/utils"
 	corepb "github.com/projecteru2/core/rpc/gen"
 	"github.com/projecteru2/core/types"
+	coreutils "github.com/projecteru2/core/utils"
 
+	"github.com/docker/go-units"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 )
 
+// largeFile is a local file to send, files are read while sending
+type largeFile struct {
+	src   string
+	dst   string
+	mode  *corepb.FileMode
+	owner *corepb.FileOwner
+}
+
 type sendLargeWorkloadsOptions struct {
 	client corepb.CoreRPCClient
 	// workload ids
-	ids     []string
-	dst     string
-	content []byte
-	modes   *corepb.FileMode
-	owners  *corepb.FileOwner
+	ids   []string
+	files []*largeFile
+	// verify sha256 of files in workloads after sending
+	verify bool
 }
 
+// run sends files one by one, core only takes one file in a stream
 func (o *sendLargeWorkloadsOptions) run(ctx context.Context) error {
-	stream, err := o.client.SendLargeFile(ctx)
+	for _, f := range o.files {
+		if err := o.send(ctx, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *sendLargeWorkloadsOptions) send(ctx context.Context, f *largeFile) error {
+	file, err := os.Open(f.src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size == 0 {
+		return fmt.Errorf("[SendLarge] %s is empty, use send instead", f.src)
+	}
+
+	// checksum is calculated while sending, so the file is read only once
+	hash := sha256.New()
+	progress := utils.NewProgress(f.dst, size)
+	err = streamLargeFile(ctx, o.client, o.ids, f.dst, io.TeeReader(file, io.MultiWriter(hash, progress)), size, f.mode, f.owner)
+	throughput := progress.Done()
+	if err != nil {
+		logrus.Errorf("[SendLarge] Failed send %s", f.dst)
+		return err
+	}
+	logrus.Infof("[SendLarge] Send %s to %s success, %s at %s/s", f.src, f.dst, units.BytesSize(float64(size)), units.BytesSize(throughput))
+
+	if !o.verify {
+		return nil
+	}
+	return o.verifyChecksum(ctx, f.dst, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// verifyChecksum checks sha256 of dst in each workload by sha256sum
+func (o *sendLargeWorkloadsOptions) verifyChecksum(ctx context.Context, dst, sum string) error {
+	failed := 0
+	for _, id := range o.ids {
+		out, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code, err := runInWorkload(ctx, o.client, &corepb.ExecuteWorkloadOptions{WorkloadId: id, Commands: []string{"sha256sum", dst}}, out, stderr)
+		switch {
+		case err != nil:
+			logrus.Errorf("[SendLarge] Verify %s in %s failed %v", dst, coreutils.ShortID(id), err)
+		case code != 0:
+			logrus.Errorf("[SendLarge] Verify %s in %s failed, sha256sum exited with %d, %s", dst, coreutils.ShortID(id), code, strings.TrimSpace(stderr.String()))
+		case strings.SplitN(strings.TrimSpace(out.String()), " ", 2)[0] != sum:
+			logrus.Errorf("[SendLarge] Checksum of %s in %s mismatched, expected %s, got %s", dst, coreutils.ShortID(id), sum, strings.TrimSpace(out.String()))
+		default:
+			logrus.Infof("[SendLarge] Checksum of %s in %s verified", dst, coreutils.ShortID(id))
+			continue
+		}
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("[SendLarge] verify %s failed in %d workload(s)", dst, failed)
+	}
+	return nil
+}
+
+// streamLargeFile sends size bytes read from r to dst of workloads chunk by chunk,
+// so the file doesn't have to be in memory, returns error if sending to any workload failed
+func streamLargeFile(ctx context.Context, client corepb.CoreRPCClient, ids []string, dst string, r io.Reader, size int64, mode *corepb.FileMode, owner *corepb.FileOwner) error {
+	stream, err := client.SendLargeFile(ctx)
 	if err != nil {
-		logrus.Errorf("[SendLarge] Failed send %s", o.dst)
 		return err
 	}
 
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	defer wg.Wait()
+	failed := []string{}
+	done := make(chan error, 1)
 	go func() {
-		defer wg.Done()
 		for {
 			msg, err := stream.Recv()
 			if err == io.EOF {
-				break
+				done <- nil
+				return
 			}
 			if err != nil {
+				done <- err
 				return
 			}
-
 			if msg.Error != "" {
-				logrus.Errorf("[SendLarge] Failed send %s to %s", msg.Path, msg.Id)
-			} else {
-				logrus.Infof("[SendLarge] Send %s to %s success", msg.Path, msg.Id)
+				failed = append(failed, fmt.Sprintf("%s: %s", msg.Id, msg.Error))
 			}
 		}
 	}()
 
-	fileOptions := o.toSendLargeFileChunks()
-	for _, chunk := range fileOptions {
-		err := stream.Send(chunk)
+	buf := make([]byte, types.SendLargeFileChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := &corepb.FileOptions{
+				Ids:   ids,
+				Dst:   dst,
+				Size:  size,
+				Mode:  mode,
+				Owner: owner,
+				Chunk: buf[:n],
+			}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
 		if err != nil {
-			logrus.Errorf("[SendLarge] Failed send %s", chunk.Dst)
 			return err
 		}
 	}
-	return stream.CloseSend()
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	if err := <-done; err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("send %s failed, %s", dst, strings.Join(failed, ", "))
+	}
+	return nil
 }
 
-func (o *sendLargeWorkloadsOptions) toSendLargeFileChunks() []*corepb.FileOptions {
-	maxChunkSize := types.SendLargeFileChunkSize
-	ret := make([]*corepb.FileOptions, 0)
-	for idx := 0; idx < len(o.content); idx += maxChunkSize {
-		fileOption := &corepb.FileOptions{
-			Ids:   o.ids,
-			Dst:   o.dst,
-			Size:  int64(len(o.content)),
-			Mode:  o.modes,
-			Owner: o.owners,
+// parseLargeFiles parses src_path:dst_path[:mode[:uid:gid]],
+// mode of local file is used if not given
+func parseLargeFiles(files []string) ([]*largeFile, error) {
+	result := []*largeFile{}
+	for _, file := range files {
+		ps := strings.Split(file, ":")
+		if len(ps) < 2 || len(ps) == 4 || len(ps) > 5 {
+			return nil, fmt.Errorf("[SendLarge] invalid file %s, should be src_path:dst_path[:mode[:uid:gid]]", file)
 		}
-		if idx+maxChunkSize > len(o.content) {
-			fileOption.Chunk = o.content[idx:]
+		f := &largeFile{src: ps[0], dst: ps[1], mode: &corepb.FileMode{}, owner: &corepb.FileOwner{}}
+		if len(ps) >= 3 {
+			mode, err := strconv.ParseInt(ps[2], 8, 0)
+			if err != nil {
+				return nil, fmt.Errorf("[SendLarge] invalid mode of %s, %v", file, err)
+			}
+			f.mode.Mode = mode
 		} else {
-			fileOption.Chunk = o.content[idx : idx+maxChunkSize]
+			info, err := os.Stat(f.src)
+			if err != nil {
+				return nil, err
+			}
+			f.mode.Mode = int64(info.Mode().Perm())
+		}
+		if len(ps) == 5 {
+			uid, err := strconv.ParseInt(ps[3], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("[SendLarge] invalid uid of %s, %v", file, err)
+			}
+			gid, err := strconv.ParseInt(ps[4], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("[SendLarge] invalid gid of %s, %v", file, err)
+			}
+			f.owner.Uid, f.owner.Gid = int32(uid), int32(gid)
 		}
-		ret = append(ret, fileOption)
+		result = append(result, f)
 	}
-	return ret
+	return result, nil
 }
 
 func cmdWorkloadSendLarge(c *cli.Context) error {
@@ -91,12 +212,12 @@ func cmdWorkloadSendLarge(c *cli.Context) error {
 		return err
 	}
 
-	content, modes, owners := utils.GenerateFileOptions(c)
-	if len(content) == 0 {
-		return fmt.Errorf("files should not be empty")
+	files, err := parseLargeFiles(c.StringSlice("file"))
+	if err != nil {
+		return err
 	}
-	if len(content) >= 2 {
-		return fmt.Errorf("can not send multiple files at the same time")
+	if len(files) == 0 {
+		return fmt.Errorf("files should not be empty")
 	}
 
 	ids := c.Args().Slice()
@@ -104,19 +225,11 @@ func cmdWorkloadSendLarge(c *cli.Context) error {
 		return fmt.Errorf("Workload ID(s) should not be empty")
 	}
 
-	targetFileName := func() string {
-		for key := range content {
-			return key
-		}
-		return ""
-	}()
 	o := &sendLargeWorkloadsOptions{
-		client:  client,
-		ids:     ids,
-		dst:     targetFileName,
-		content: content[targetFileName],
-		modes:   modes[targetFileName],
-		owners:  owners[targetFileName],
+		client: client,
+		ids:    ids,
+		files:  files,
+		verify: !c.Bool("no-verify"),
 	}
 	return o.run(c.Context)
 }
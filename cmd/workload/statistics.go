@@ -0,0 +1,87 @@
+package workload
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+	resourcetypes "github.com/projecteru2/core/resource/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// groupKey returns the group of a workload
+type groupKey func(w *corepb.Workload) string
+
+// parseGroupBy parses node, pod, entry or label:<key>,
+// empty means all workloads are in one group
+func parseGroupBy(groupBy string) (groupKey, error) {
+	switch {
+	case groupBy == "":
+		return func(*corepb.Workload) string { return "" }, nil
+	case groupBy == "node":
+		return func(w *corepb.Workload) string { return w.Nodename }, nil
+	case groupBy == "pod":
+		return func(w *corepb.Workload) string { return w.Podname }, nil
+	case groupBy == "entry":
+		return func(w *corepb.Workload) string {
+			_, entry, _, err := coreutils.ParseWorkloadName(w.Name)
+			if err != nil {
+				return ""
+			}
+			return entry
+		}, nil
+	case strings.HasPrefix(groupBy, "label:") && groupBy != "label:":
+		key := strings.TrimPrefix(groupBy, "label:")
+		return func(w *corepb.Workload) string { return w.Labels[key] }, nil
+	default:
+		return nil, fmt.Errorf("invalid group by %s, should be node, pod, entry or label:<key>", groupBy)
+	}
+}
+
+// workloadsStatistics counts workloads and sums their requests and limits of every plugin by groups,
+// plugins missing in some workloads are just not summed for them
+func workloadsStatistics(workloads []*corepb.Workload, key groupKey) []*types.WorkloadsStatistics {
+	groups := map[string]*types.WorkloadsStatistics{}
+	for _, w := range workloads {
+		group := key(w)
+		stat, ok := groups[group]
+		if !ok {
+			stat = &types.WorkloadsStatistics{Group: group, Resources: map[string]map[string]float64{}}
+			groups[group] = stat
+		}
+		stat.Count++
+
+		if w.Resources == "" {
+			continue
+		}
+		res := resourcetypes.Resources{}
+		if err := json.Unmarshal([]byte(w.Resources), &res); err != nil {
+			logrus.Warnf("[Statistics] Invalid resources of workload %s: %v", w.Id, err)
+			continue
+		}
+		for plugin, params := range res {
+			for param, value := range params {
+				v, ok := value.(float64)
+				if !ok || !(strings.HasSuffix(param, "_request") || strings.HasSuffix(param, "_limit")) {
+					continue
+				}
+				if stat.Resources[plugin] == nil {
+					stat.Resources[plugin] = map[string]float64{}
+				}
+				stat.Resources[plugin][param] += v
+			}
+		}
+	}
+
+	stats := []*types.WorkloadsStatistics{}
+	for _, stat := range groups {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Group < stats[j].Group })
+	return stats
+}
@@ -0,0 +1,71 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+	corepb "github.com/projecteru2/core/rpc/gen"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	w := &corepb.Workload{Name: "app_web_abcdef", Nodename: "n1", Podname: "p1", Labels: map[string]string{"team": "a"}}
+	cases := map[string]string{
+		"":           "",
+		"node":       "n1",
+		"pod":        "p1",
+		"entry":      "web",
+		"label:team": "a",
+		"label:none": "",
+	}
+	for groupBy, expected := range cases {
+		key, err := parseGroupBy(groupBy)
+		if err != nil {
+			t.Errorf("parseGroupBy(%q) error = %v", groupBy, err)
+			continue
+		}
+		if got := key(w); got != expected {
+			t.Errorf("parseGroupBy(%q) = %q, want %q", groupBy, got, expected)
+		}
+	}
+	for _, groupBy := range []string{"label:", "image", "nodes"} {
+		if _, err := parseGroupBy(groupBy); err == nil {
+			t.Errorf("parseGroupBy(%q) should fail", groupBy)
+		}
+	}
+}
+
+func TestWorkloadsStatistics(t *testing.T) {
+	workloads := []*corepb.Workload{
+		{Nodename: "n1", Resources: `{"cpumem": {"cpu_request": 1, "cpu_limit": 2, "memory_request": 1024, "cpu_map": {"0": 100}}, "storage": {"storage_request": 10}}`},
+		{Nodename: "n1", Resources: `{"cpumem": {"cpu_request": 0.5, "cpu_limit": 0.5, "memory_request": 1024}}`},
+		{Nodename: "n2", Resources: `{"cpumem": {"cpu_request": 2}, "gpu": {"gpu_request": 1, "gpu_model": "a100"}}`},
+		{Nodename: "n2", Resources: `invalid`},
+		{Nodename: "n3"},
+	}
+
+	key, _ := parseGroupBy("node")
+	stats := workloadsStatistics(workloads, key)
+	expected := []*types.WorkloadsStatistics{
+		{Group: "n1", Count: 2, Resources: map[string]map[string]float64{
+			"cpumem":  {"cpu_request": 1.5, "cpu_limit": 2.5, "memory_request": 2048},
+			"storage": {"storage_request": 10},
+		}},
+		{Group: "n2", Count: 2, Resources: map[string]map[string]float64{
+			"cpumem": {"cpu_request": 2},
+			"gpu":    {"gpu_request": 1},
+		}},
+		{Group: "n3", Count: 1, Resources: map[string]map[string]float64{}},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		for _, s := range stats {
+			t.Logf("%+v", s)
+		}
+		t.Error("workloadsStatistics() is not as expected")
+	}
+
+	key, _ = parseGroupBy("")
+	if stats := workloadsStatistics(workloads, key); len(stats) != 1 || stats[0].Count != len(workloads) {
+		t.Errorf("workloadsStatistics() without groups = %+v", stats)
+	}
+}
@@ -3,11 +3,15 @@ package workload
 import (
 	"context"
 	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/projecteru2/cli/cmd/utils"
 	"github.com/projecteru2/cli/describe"
 	corepb "github.com/projecteru2/core/rpc/gen"
 
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 )
 
@@ -53,9 +57,88 @@ type setWorkloadsStatusOptions struct {
 	ttl       int64
 	networks  map[string]string
 	extension []byte
+
+	// interval in seconds, set status every interval if given
+	interval     int
+	probes       []probe
+	probeTimeout time.Duration
 }
 
 func (o *setWorkloadsStatusOptions) run(ctx context.Context) error {
+	if o.interval == 0 {
+		if len(o.probes) > 0 {
+			o.probe(ctx)
+		}
+		resp, err := o.setStatus(ctx)
+		if err != nil {
+			return err
+		}
+		describe.WorkloadStatuses(resp.Status...)
+		return nil
+	}
+
+	if o.ttl > 0 && o.ttl <= int64(o.interval) {
+		logrus.Warnf("[SetStatus] ttl %d is not longer than interval %d, status may expire between heartbeats", o.ttl, o.interval)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	timer := time.NewTicker(time.Duration(o.interval) * time.Second)
+	defer timer.Stop()
+
+	for {
+		o.heartbeat(ctx)
+		select {
+		case <-ctx.Done():
+			return o.markUnhealthy()
+		case <-timer.C:
+		}
+	}
+}
+
+// heartbeat probes and sets status once, errors are only logged to keep heartbeating
+func (o *setWorkloadsStatusOptions) heartbeat(ctx context.Context) {
+	if len(o.probes) > 0 {
+		o.probe(ctx)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if _, err := o.setStatus(ctx); err != nil {
+		logrus.Errorf("[SetStatus] Failed to set status: %v", err)
+	}
+}
+
+// probe updates running and healthy by probes, logs when they change
+func (o *setWorkloadsStatusOptions) probe(ctx context.Context) {
+	running, healthy, errs := runProbes(ctx, o.probes, o.probeTimeout)
+	for _, err := range errs {
+		logrus.Warnf("[SetStatus] %v", err)
+	}
+	if running != o.running || healthy != o.healthy {
+		logrus.Infof("[SetStatus] Status changed, running: %v, healthy: %v", running, healthy)
+	}
+	o.running, o.healthy = running, healthy
+}
+
+// markUnhealthyTimeout limits how long to wait for marking unhealthy on exit
+const markUnhealthyTimeout = 10 * time.Second
+
+// markUnhealthy is called on exit, a new context is used since the old one is canceled
+func (o *setWorkloadsStatusOptions) markUnhealthy() error {
+	ctx, cancel := context.WithTimeout(context.Background(), markUnhealthyTimeout)
+	defer cancel()
+
+	o.healthy = false
+	if _, err := o.setStatus(ctx); err != nil {
+		return fmt.Errorf("[SetStatus] failed to mark workloads unhealthy on exit: %v", err)
+	}
+	logrus.Infof("[SetStatus] Workloads marked unhealthy on exit")
+	return nil
+}
+
+func (o *setWorkloadsStatusOptions) setStatus(ctx context.Context) (*corepb.WorkloadsStatus, error) {
 	opts := &corepb.SetWorkloadsStatusOptions{Status: []*corepb.WorkloadStatus{}}
 	for _, id := range o.ids {
 		s := &corepb.WorkloadStatus{
@@ -68,14 +151,7 @@ func (o *setWorkloadsStatusOptions) run(ctx context.Context) error {
 		}
 		opts.Status = append(opts.Status, s)
 	}
-
-	resp, err := o.client.SetWorkloadsStatus(ctx, opts)
-	if err != nil {
-		return err
-	}
-
-	describe.WorkloadStatuses(resp.Status...)
-	return nil
+	return o.client.SetWorkloadsStatus(ctx, opts)
 }
 
 func cmdWorkloadSetStatus(c *cli.Context) error {
@@ -88,15 +164,30 @@ func cmdWorkloadSetStatus(c *cli.Context) error {
 	if len(ids) == 0 {
 		return fmt.Errorf("Workload ID(s) should not be empty")
 	}
+	if c.Int("interval") < 0 {
+		return fmt.Errorf("[SetStatus] interval %d should not be negative", c.Int("interval"))
+	}
+
+	probes := []probe{}
+	for _, s := range c.StringSlice("probe") {
+		p, err := parseProbe(s)
+		if err != nil {
+			return err
+		}
+		probes = append(probes, p)
+	}
 
 	o := &setWorkloadsStatusOptions{
-		client:    client,
-		ids:       ids,
-		running:   c.Bool("running"),
-		healthy:   c.Bool("healthy"),
-		ttl:       c.Int64("ttl"),
-		networks:  utils.SplitEquality(c.StringSlice("network")),
-		extension: []byte(c.String("extension")),
+		client:       client,
+		ids:          ids,
+		running:      c.Bool("running"),
+		healthy:      c.Bool("healthy"),
+		ttl:          c.Int64("ttl"),
+		networks:     utils.SplitEquality(c.StringSlice("network")),
+		extension:    []byte(c.String("extension")),
+		interval:     c.Int("interval"),
+		probes:       probes,
+		probeTimeout: c.Duration("probe-timeout"),
 	}
 	return o.run(c.Context)
 }
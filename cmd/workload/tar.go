@@ -0,0 +1,239 @@
+package workload
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// writeTar packs src into w, entries are named under the base name of src like docker cp does,
+// modes, owners and modification times are kept, owners are numeric so they're the same in workloads
+func writeTar(w io.Writer, src string) error {
+	tw := tar.NewWriter(w)
+	base := filepath.Base(src)
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		header.Uname, header.Gname = "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// extractTar extracts tarball into root, entries escaping root are refused,
+// existing files are kept unless overwrite.
+// Modes and modification times are kept, so are owners if running as root
+func extractTar(r io.Reader, root string, overwrite bool) ([]*types.CopiedFile, error) {
+	files := []*types.CopiedFile{}
+	// modes of directories are set after extracting, they may be read only
+	dirs := []*tar.Header{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+
+		target, err := securePath(root, header.Name)
+		if err != nil {
+			return files, err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirInRoot(root, target); err != nil {
+				return files, err
+			}
+			dirs = append(dirs, header)
+		case tar.TypeReg:
+			if err := prepareTarget(root, target, overwrite); err != nil {
+				return files, err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+			if err != nil {
+				return files, err
+			}
+			n, err := io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return files, err
+			}
+			if err := setAttributes(target, header); err != nil {
+				return files, err
+			}
+			files = append(files, &types.CopiedFile{Path: target, Size: n})
+		case tar.TypeSymlink:
+			if err := prepareTarget(root, target, overwrite); err != nil {
+				return files, err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return files, err
+			}
+			if err := setAttributes(target, header); err != nil {
+				return files, err
+			}
+			files = append(files, &types.CopiedFile{Path: target})
+		default:
+			logrus.Warnf("[Extract] Skip %s, type %c is not supported", header.Name, header.Typeflag)
+		}
+	}
+
+	// directories may be replaced by symlinks of later entries, attributes are only set on real directories in root
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return files, err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		target, _ := securePath(root, dirs[i].Name)
+		if info, err := os.Lstat(target); err != nil || !info.IsDir() {
+			logrus.Warnf("[Extract] Skip attributes of %s, it's not a directory any more", dirs[i].Name)
+			continue
+		}
+		if err := checkInRoot(realRoot, target); err != nil {
+			return files, err
+		}
+		if err := setAttributes(target, dirs[i]); err != nil {
+			return files, err
+		}
+	}
+	// drain the padding after the end of archive
+	_, err = io.Copy(io.Discard, r)
+	return files, err
+}
+
+func setAttributes(target string, header *tar.Header) error {
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(target, header.Uid, header.Gid); err != nil {
+			return err
+		}
+	}
+	if header.Typeflag == tar.TypeSymlink {
+		return nil
+	}
+	if err := os.Chmod(target, header.FileInfo().Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// securePath joins name to root, refuses names like ../../etc/passwd
+func securePath(root, name string) (string, error) {
+	clean := filepath.Clean(name)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal path %s in tarball", name)
+	}
+	return filepath.Join(root, clean), nil
+}
+
+// prepareTarget makes sure parent of target is inside root even following symlinks,
+// so files won't be written through a symlink extracted before,
+// and removes the existing target if overwrite
+func prepareTarget(root, target string, overwrite bool) error {
+	if err := mkdirInRoot(root, filepath.Dir(target)); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(target); err == nil {
+		if !overwrite {
+			return fmt.Errorf("%s already exists", target)
+		}
+		return os.RemoveAll(target)
+	}
+	return nil
+}
+
+// mkdirInRoot creates dir and its parents one by one, each of them is checked to be a directory inside root
+// even following symlinks, so nothing is created or changed outside root through a symlink extracted before
+func mkdirInRoot(root, dir string) error {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		if _, err := os.Lstat(current); os.IsNotExist(err) {
+			if err := os.Mkdir(current, 0700); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+		if err := checkInRoot(realRoot, current); err != nil {
+			return err
+		}
+		info, err := os.Stat(current)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", current)
+		}
+	}
+	return nil
+}
+
+// checkInRoot refuses path resolved outside realRoot following symlinks
+func checkInRoot(realRoot, path string) error {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if realPath != realRoot && !strings.HasPrefix(realPath, realRoot+string(filepath.Separator)) {
+		return fmt.Errorf("illegal path %s in tarball, it's outside of root", path)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package workload
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	mode     int64
+	content  string
+}
+
+func buildTar(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     e.mode,
+			Size:     int64(len(e.content)),
+			ModTime:  time.Unix(0, 0),
+			Uid:      os.Getuid(),
+			Gid:      os.Getgid(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestSecurePath(t *testing.T) {
+	cases := []struct {
+		name string
+		ok   bool
+	}{
+		{"a/b", true},
+		{"./a/../b", true},
+		{"..", false},
+		{"../etc/passwd", false},
+		{"a/../../etc", false},
+		{"/etc/passwd", false},
+	}
+	for _, c := range cases {
+		target, err := securePath("/root", c.name)
+		if c.ok != (err == nil) {
+			t.Errorf("securePath(%q) = %q, %v", c.name, target, err)
+		}
+	}
+}
+
+// outside returns a directory outside root with its mode and modification time to check
+func outside(t *testing.T) (string, os.FileMode, time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, info.Mode(), info.ModTime()
+}
+
+func assertUnchanged(t *testing.T, dir string, mode os.FileMode, modTime time.Time) {
+	t.Helper()
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != mode || !info.ModTime().Equal(modTime) {
+		t.Errorf("%s is changed, mode %v -> %v, mtime %v -> %v", dir, mode, info.Mode(), modTime, info.ModTime())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("%s is written, %d entries", dir, len(entries))
+	}
+}
+
+func TestExtractTarSymlinkThenDir(t *testing.T) {
+	out, mode, modTime := outside(t)
+	root := t.TempDir()
+	tarball := buildTar(t,
+		tarEntry{name: "x", typeflag: tar.TypeSymlink, linkname: out, mode: 0777},
+		tarEntry{name: "x/", typeflag: tar.TypeDir, mode: 0777},
+	)
+	if _, err := extractTar(tarball, root, true); err == nil {
+		t.Error("extractTar() should refuse dir through symlink")
+	}
+	assertUnchanged(t, out, mode, modTime)
+}
+
+func TestExtractTarDirReplacedBySymlink(t *testing.T) {
+	out, mode, modTime := outside(t)
+	root := t.TempDir()
+	tarball := buildTar(t,
+		tarEntry{name: "x/", typeflag: tar.TypeDir, mode: 0777},
+		tarEntry{name: "x", typeflag: tar.TypeSymlink, linkname: out, mode: 0777},
+	)
+	if _, err := extractTar(tarball, root, true); err != nil {
+		t.Fatal(err)
+	}
+	assertUnchanged(t, out, mode, modTime)
+}
+
+func TestExtractTarFileThroughSymlink(t *testing.T) {
+	out, mode, modTime := outside(t)
+	root := t.TempDir()
+	tarball := buildTar(t,
+		tarEntry{name: "x", typeflag: tar.TypeSymlink, linkname: out, mode: 0777},
+		tarEntry{name: "x/y/passwd", typeflag: tar.TypeReg, mode: 0644, content: "pwned"},
+	)
+	if _, err := extractTar(tarball, root, true); err == nil {
+		t.Error("extractTar() should refuse file through symlink")
+	}
+	assertUnchanged(t, out, mode, modTime)
+}
+
+func TestExtractTarIllegalName(t *testing.T) {
+	tarball := buildTar(t, tarEntry{name: "../escape", typeflag: tar.TypeReg, mode: 0644, content: "x"})
+	if _, err := extractTar(tarball, t.TempDir(), true); err == nil {
+		t.Error("extractTar() should refuse ../escape")
+	}
+}
+
+func TestExtractTarOverwrite(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "f"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entry := tarEntry{name: "f", typeflag: tar.TypeReg, mode: 0644, content: "new"}
+	if _, err := extractTar(buildTar(t, entry), root, false); err == nil {
+		t.Error("extractTar() should keep existing file without overwrite")
+	}
+	if _, err := extractTar(buildTar(t, entry), root, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(root, "f")); string(b) != "new" {
+		t.Errorf("f = %q, want new", b)
+	}
+}
+
+func TestWriteExtractTar(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "a.txt"), []byte("hello"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/a.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(src, "sub"), 0500); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(src, "sub"), 0755) //nolint
+
+	buf := &bytes.Buffer{}
+	if err := writeTar(buf, src); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	files, err := extractTar(buf, root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(root, "src", "sub"), 0755) //nolint
+	if len(files) != 2 {
+		t.Errorf("extracted %d files, want 2", len(files))
+	}
+
+	b, err := os.ReadFile(filepath.Join(root, "src", "link"))
+	if err != nil || string(b) != "hello" {
+		t.Errorf("read link = %q, %v", b, err)
+	}
+	info, err := os.Stat(filepath.Join(root, "src", "sub", "a.txt"))
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("a.txt mode = %v, %v", info.Mode(), err)
+	}
+	info, err = os.Stat(filepath.Join(root, "src", "sub"))
+	if err != nil || info.Mode().Perm() != 0500 {
+		t.Errorf("sub mode = %v, %v", info.Mode(), err)
+	}
+}
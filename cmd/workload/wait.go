@@ -0,0 +1,36 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/projecteru2/cli/cmd/utils"
+	corepb "github.com/projecteru2/core/rpc/gen"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// waitHealthy waits workloads to be running and healthy,
+// returns error with the reason of each workload if not
+func waitHealthy(ctx context.Context, client corepb.CoreRPCClient, ids []string, timeout time.Duration) error {
+	logrus.Infof("[Wait] Waiting %d workload(s) to be healthy in %v", len(ids), timeout)
+	healths, err := utils.WaitHealthy(ctx, client, ids, timeout)
+	if err != nil {
+		return fmt.Errorf("[Wait] watch status failed %v", err)
+	}
+	failed := 0
+	for _, h := range healths {
+		if h.OK() {
+			logrus.Infof("[Wait] %s is running and healthy", coreutils.ShortID(h.ID))
+			continue
+		}
+		failed++
+		logrus.Errorf("[Wait] %s %s", coreutils.ShortID(h.ID), h.Reason())
+	}
+	if failed > 0 {
+		return fmt.Errorf("[Wait] %d of %d workload(s) are not healthy", failed, len(ids))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package describe
+
+import (
+	"os"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/docker/go-units"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// CopyManifests describes files copied from workloads
+// output format can be json or yaml or table
+func CopyManifests(manifests ...*types.CopyManifest) {
+	switch {
+	case isJSON():
+		describeAsJSON(manifests)
+	case isYAML():
+		describeAsYAML(manifests)
+	default:
+		describeCopyManifests(manifests)
+	}
+}
+
+func describeCopyManifests(manifests []*types.CopyManifest) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Workload", "Source", "File", "Size", "Error"})
+	for _, m := range manifests {
+		sources, files, sizes := []string{}, []string{}, []string{}
+		for _, f := range m.Files {
+			sources = append(sources, f.Source)
+			files = append(files, f.Path)
+			sizes = append(sizes, units.BytesSize(float64(f.Size)))
+		}
+		t.AppendRow(table.Row{coreutils.ShortID(m.ID), strings.Join(sources, "\n"), strings.Join(files, "\n"), strings.Join(sizes, "\n"), strings.Join(m.Errors, "\n")})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
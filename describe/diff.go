@@ -0,0 +1,193 @@
+package describe
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/projecteru2/cli/types"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+const (
+	// diffContext is how many unchanged lines are shown around changes
+	diffContext = 3
+	// maxDiffCells limits the size of LCS table, larger inputs are diffed as replaced entirely
+	maxDiffCells = 16 * 1024 * 1024
+)
+
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// WorkloadsDiff describes differences between two workloads
+// output format can be json or yaml or unified diff, colored if color is set
+func WorkloadsDiff(diff *types.WorkloadsDiff, color bool) {
+	switch {
+	case isJSON():
+		describeAsJSON(diff)
+	case isYAML():
+		describeAsYAML(diff)
+	default:
+		describeUnifiedDiff(os.Stdout, diff, color)
+	}
+}
+
+func describeUnifiedDiff(w io.Writer, diff *types.WorkloadsDiff, color bool) {
+	paint := func(c text.Color, s string) string {
+		if !color {
+			return s
+		}
+		return c.Sprint(s)
+	}
+	a := fmt.Sprintf("%s %s", diff.A.Name, coreutils.ShortID(diff.A.ID))
+	b := fmt.Sprintf("%s %s", diff.B.Name, coreutils.ShortID(diff.B.ID))
+	for _, f := range diff.Fields {
+		fmt.Fprintln(w, paint(text.Bold, fmt.Sprintf("--- a/%s\t%s", f.Field, a)))
+		fmt.Fprintln(w, paint(text.Bold, fmt.Sprintf("+++ b/%s\t%s", f.Field, b)))
+		lines := diffLines(f.A, f.B)
+		for _, h := range diffHunks(lines) {
+			fmt.Fprintln(w, paint(text.FgCyan, h.header))
+			for _, l := range lines[h.start:h.end] {
+				switch l.kind {
+				case '-':
+					fmt.Fprintln(w, paint(text.FgRed, "-"+l.text))
+				case '+':
+					fmt.Fprintln(w, paint(text.FgGreen, "+"+l.text))
+				default:
+					fmt.Fprintln(w, " "+l.text)
+				}
+			}
+		}
+	}
+}
+
+// diffLines diffs a and b by longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	lines := []diffLine{}
+	n, m := len(a), len(b)
+	if n*m > maxDiffCells {
+		for _, s := range a {
+			lines = append(lines, diffLine{'-', s})
+		}
+		for _, s := range b {
+			lines = append(lines, diffLine{'+', s})
+		}
+		return lines
+	}
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+type diffHunk struct {
+	start, end int
+	header     string
+}
+
+// diffHunks groups changes with diffContext lines around,
+// changes closer than twice of diffContext are in the same hunk
+func diffHunks(lines []diffLine) []*diffHunk {
+	hunks := []*diffHunk{}
+	i := 0
+	for {
+		for i < len(lines) && lines[i].kind == ' ' {
+			i++
+		}
+		if i == len(lines) {
+			return hunks
+		}
+
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		if len(hunks) > 0 && start < hunks[len(hunks)-1].end {
+			start = hunks[len(hunks)-1].end
+		}
+		changed := i
+		for k := i; k < len(lines); k++ {
+			if lines[k].kind != ' ' {
+				changed = k + 1
+			} else if k-changed >= 2*diffContext {
+				break
+			}
+		}
+		end := changed + diffContext
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		aStart, bStart := 1, 1
+		for _, l := range lines[:start] {
+			if l.kind != '+' {
+				aStart++
+			}
+			if l.kind != '-' {
+				bStart++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, l := range lines[start:end] {
+			if l.kind != '+' {
+				aCount++
+			}
+			if l.kind != '-' {
+				bCount++
+			}
+		}
+		// an empty range starts at the line before, as diff -u does
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		hunks = append(hunks, &diffHunk{
+			start:  start,
+			end:    end,
+			header: fmt.Sprintf("@@ -%d,%d +%d,%d @@", aStart, aCount, bStart, bCount),
+		})
+		i = end
+	}
+}
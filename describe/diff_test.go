@@ -0,0 +1,69 @@
+package describe
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func seqLines(from, to int) []string {
+	lines := []string{}
+	for i := from; i <= to; i++ {
+		lines = append(lines, fmt.Sprintf("l%d", i))
+	}
+	return lines
+}
+
+func concat(parts ...[]string) []string {
+	lines := []string{}
+	for _, p := range parts {
+		lines = append(lines, p...)
+	}
+	return lines
+}
+
+// unified renders hunks of a and b like diff -u without file headers
+func unified(a, b []string) string {
+	out := []string{}
+	lines := diffLines(a, b)
+	for _, h := range diffHunks(lines) {
+		out = append(out, h.header)
+		for _, l := range lines[h.start:h.end] {
+			out = append(out, string(l.kind)+l.text)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// expected outputs are from diff -u
+func TestDiffHunks(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     []string
+		expected string
+	}{
+		{"same", seqLines(1, 5), seqLines(1, 5), ""},
+		{"both empty", nil, nil, ""},
+		{"insert only", seqLines(1, 5), concat(seqLines(1, 2), []string{"X"}, seqLines(3, 5)),
+			"@@ -1,5 +1,6 @@\n l1\n l2\n+X\n l3\n l4\n l5"},
+		{"delete only", seqLines(1, 5), concat(seqLines(1, 2), seqLines(4, 5)),
+			"@@ -1,5 +1,4 @@\n l1\n l2\n-l3\n l4\n l5"},
+		{"changes close enough to merge", seqLines(1, 20), concat(seqLines(1, 4), []string{"X"}, seqLines(6, 11), []string{"Y"}, seqLines(13, 20)),
+			"@@ -2,14 +2,14 @@\n l2\n l3\n l4\n-l5\n+X\n l6\n l7\n l8\n l9\n l10\n l11\n-l12\n+Y\n l13\n l14\n l15"},
+		{"changes too far to merge", seqLines(1, 20), concat(seqLines(1, 4), []string{"X"}, seqLines(6, 12), []string{"Y"}, seqLines(14, 20)),
+			"@@ -2,7 +2,7 @@\n l2\n l3\n l4\n-l5\n+X\n l6\n l7\n l8\n@@ -10,7 +10,7 @@\n l10\n l11\n l12\n-l13\n+Y\n l14\n l15\n l16"},
+		{"empty a", nil, []string{"x", "y"}, "@@ -0,0 +1,2 @@\n+x\n+y"},
+		{"empty b", []string{"x", "y"}, nil, "@@ -1,2 +0,0 @@\n-x\n-y"},
+		{"insert at start", seqLines(1, 10), concat([]string{"X"}, seqLines(1, 10)),
+			"@@ -1,3 +1,4 @@\n+X\n l1\n l2\n l3"},
+		{"delete at end", seqLines(1, 10), seqLines(1, 9),
+			"@@ -7,4 +7,3 @@\n l7\n l8\n l9\n-l10"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := unified(c.a, c.b); got != c.expected {
+				t.Errorf("diff = \n%s\nwant\n%s", got, c.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package describe
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/projecteru2/cli/types"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// DrainPlan describes workloads to migrate by entrypoints, and if other nodes can hold them,
+// capacity of each entrypoint is checked alone
+// output format can be json or yaml or table
+func DrainPlan(groups ...*types.DrainGroup) {
+	switch {
+	case isJSON():
+		describeAsJSON(groups)
+	case isYAML():
+		describeAsYAML(groups)
+	default:
+		describeDrainPlan(groups)
+	}
+}
+
+func describeDrainPlan(groups []*types.DrainGroup) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"App/Entry", "Count", "Capacity Per Entry", "Result"})
+	for _, g := range groups {
+		result := "Fits"
+		switch {
+		case g.Error != "":
+			result = g.Error
+		case !g.Fits:
+			result = "Not enough resources on other nodes"
+		}
+		t.AppendRow(table.Row{fmt.Sprintf("%s/%s", g.Appname, g.Entrypoint), g.Count, g.Capacity, result})
+		t.AppendSeparator()
+	}
+	if len(groups) > 1 {
+		t.SetCaption("Capacity is checked per entrypoint, entrypoints compete for the same resources, all fitting is not guaranteed.")
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
+
+// DrainResults describes workloads migrated off the drained node
+// output format can be json or yaml or table
+func DrainResults(results ...*types.DrainResult) {
+	switch {
+	case isJSON():
+		describeAsJSON(results)
+	case isYAML():
+		describeAsYAML(results)
+	default:
+		describeDrainResults(results)
+	}
+}
+
+func describeDrainResults(results []*types.DrainResult) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Name/ID", "New Name/ID", "New Node", "Result"})
+	for _, r := range results {
+		result := "OK"
+		if r.Error != "" {
+			result = r.Error
+		}
+		newWorkload := ""
+		if r.NewID != "" {
+			newWorkload = fmt.Sprintf("%s\n%s", r.NewName, coreutils.ShortID(r.NewID))
+		}
+		t.AppendRow(table.Row{
+			fmt.Sprintf("%s\n%s", r.Name, coreutils.ShortID(r.ID)),
+			newWorkload,
+			r.NewNodename,
+			result,
+		})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
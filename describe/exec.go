@@ -0,0 +1,43 @@
+package describe
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// maxGroupWorkloads is how many workloads are shown in a group in table
+const maxGroupWorkloads = 5
+
+// ExecSummary describes the summary of executing commands in workloads
+// output format can be json or yaml or table
+func ExecSummary(summary *types.ExecSummary) {
+	switch {
+	case isJSON():
+		describeAsJSON(summary)
+	case isYAML():
+		describeAsYAML(summary)
+	default:
+		describeExecGroups(summary.Groups)
+	}
+}
+
+func describeExecGroups(groups []*types.ExecGroup) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Exit Code", "Output Hash", "Count", "Workloads", "Error"})
+	for _, g := range groups {
+		names := g.Workloads
+		if len(names) > maxGroupWorkloads {
+			names = append(append([]string{}, names[:maxGroupWorkloads]...), fmt.Sprintf("... and %d more", len(g.Workloads)-maxGroupWorkloads))
+		}
+		t.AppendRow(table.Row{g.ExitCode, g.OutputHash, len(g.Workloads), strings.Join(names, "\n"), g.Error})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
@@ -0,0 +1,41 @@
+package describe
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Journals describes replace journals
+// output format can be json or yaml or table
+func Journals(journals ...*types.Journal) {
+	switch {
+	case isJSON():
+		describeAsJSON(journals)
+	case isYAML():
+		describeAsYAML(journals)
+	default:
+		describeJournals(journals)
+	}
+}
+
+func describeJournals(journals []*types.Journal) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"ID", "Time", "App/Entry", "Image", "Replaced", "Rollback Of"})
+	for _, j := range journals {
+		t.AppendRow(table.Row{
+			j.ID,
+			j.CreatedAt.Format("2006-01-02 15:04:05"),
+			fmt.Sprintf("%s/%s", j.Appname, j.Entrypoint),
+			j.Image,
+			fmt.Sprintf("%d/%d", len(j.Replaced()), len(j.Entries)),
+			j.RollbackOf,
+		})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
@@ -0,0 +1,43 @@
+package describe
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// LintIssues describes issues found in specs
+// output format can be json or yaml or table
+func LintIssues(issues ...*types.LintIssue) {
+	switch {
+	case isJSON():
+		describeAsJSON(issues)
+	case isYAML():
+		describeAsYAML(issues)
+	default:
+		describeLintIssues(issues)
+	}
+}
+
+func describeLintIssues(issues []*types.LintIssue) {
+	if len(issues) == 0 {
+		fmt.Println("No issues found")
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"File:Line", "Level", "Path", "Message"})
+	for _, issue := range issues {
+		position := issue.File
+		if issue.Line > 0 {
+			position = fmt.Sprintf("%s:%d", issue.File, issue.Line)
+		}
+		t.AppendRow(table.Row{position, issue.Level, issue.Path, issue.Message})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
@@ -0,0 +1,49 @@
+package describe
+
+import (
+	"os"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Maintenance describes steps of a node maintenance
+// output format can be json or yaml or table
+func Maintenance(m *types.Maintenance) {
+	switch {
+	case isJSON():
+		describeAsJSON(m)
+	case isYAML():
+		describeAsYAML(m)
+	default:
+		describeMaintenance(m)
+	}
+}
+
+func describeMaintenance(m *types.Maintenance) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Step", "Status", "Done At", "Message"})
+	next := m.Next()
+	for _, s := range m.Steps {
+		status, doneAt, message := "Pending", "", s.Error
+		switch {
+		case s.Done:
+			status = "Done"
+			doneAt = s.DoneAt.Format("2006-01-02 15:04:05")
+		case s.Error != "":
+			status = "Failed"
+		case s == next:
+			status = "Next"
+		}
+		if s.Name == types.MaintenanceResource && len(m.Diffs) > 0 {
+			message = strings.Join(m.Diffs, "\n") + "\n" + message
+		}
+		t.AppendRow(table.Row{s.Name, status, doneAt, strings.TrimSpace(message)})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
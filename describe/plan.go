@@ -0,0 +1,136 @@
+package describe
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// DeployPlan describes deploy plans
+// output format can be json or yaml or table
+func DeployPlan(plans ...*types.DeployPlan) {
+	switch {
+	case isJSON():
+		describeAsJSON(plans)
+	case isYAML():
+		describeAsYAML(plans)
+	default:
+		for _, plan := range plans {
+			describeDeployPlan(plan)
+		}
+	}
+}
+
+func describeDeployPlan(plan *types.DeployPlan) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(fmt.Sprintf("%s/%s", plan.Appname, plan.Entrypoint))
+	t.AppendHeader(table.Row{"Option", "Value"})
+
+	appendRow := func(name string, values ...string) {
+		t.AppendRows(toTableRows([][]string{{name}, values}))
+		t.AppendSeparator()
+	}
+
+	appendRow("Image", plan.Image)
+	appendRow("Pod/Nodes", append([]string{plan.Podname}, plan.Nodes...)...)
+	if len(plan.NodeLabels) > 0 {
+		appendRow("Node Labels", mapToLines(plan.NodeLabels)...)
+	}
+	appendRow("Count/Strategy", fmt.Sprintf("%d", plan.Count), plan.Strategy)
+	appendRow("User", plan.User)
+	appendRow("Commands", plan.Commands...)
+	if plan.Dir != "" {
+		appendRow("Dir", plan.Dir)
+	}
+	appendRow("Privileged", fmt.Sprintf("%v", plan.Privileged))
+	if plan.Restart != "" {
+		appendRow("Restart", plan.Restart)
+	}
+	appendRow("Env", plan.Env...)
+	appendRow("Networks", mapToLines(plan.Networks)...)
+	appendRow("Publish", plan.Publish...)
+	appendRow("Labels", mapToLines(plan.Labels)...)
+
+	resources := []string{}
+	plugins := []string{}
+	for plugin := range plan.Resources {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	for _, plugin := range plugins {
+		resources = append(resources, parse(plugin, plan.Resources[plugin])...)
+	}
+	appendRow("Resources", resources...)
+
+	files := []string{}
+	for _, f := range plan.Files {
+		files = append(files, fmt.Sprintf("%s (%d bytes, mode %s, owner %d:%d)", f.Path, f.Size, f.Mode, f.UID, f.GID), "  sha256:"+f.SHA256)
+	}
+	appendRow("Files", files...)
+
+	if plan.Hook != nil {
+		hooks := []string{}
+		for _, cmd := range plan.Hook.AfterStart {
+			hooks = append(hooks, "after_start: "+cmd)
+		}
+		for _, cmd := range plan.Hook.BeforeStop {
+			hooks = append(hooks, "before_stop: "+cmd)
+		}
+		hooks = append(hooks, fmt.Sprintf("force: %v", plan.Hook.Force))
+		appendRow("Hook", hooks...)
+	}
+	if plan.HealthCheck != nil {
+		hc := plan.HealthCheck
+		appendRow("HealthCheck",
+			"tcp_ports: "+strings.Join(hc.TcpPorts, ","),
+			"http_port: "+hc.HttpPort,
+			"url: "+hc.Url,
+			fmt.Sprintf("code: %d", hc.Code),
+		)
+	}
+	if len(plan.AfterCreate) > 0 {
+		appendRow("After Create", plan.AfterCreate...)
+	}
+
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	if plan.Existing == 0 {
+		fmt.Println("No existing workloads, all workloads will be created")
+		return
+	}
+	if len(plan.Diffs) == 0 {
+		fmt.Printf("%d existing workload(s), no changes\n", plan.Existing)
+		return
+	}
+	fmt.Printf("%d existing workload(s), changes:\n", plan.Existing)
+
+	d := table.NewWriter()
+	d.SetOutputMirror(os.Stdout)
+	d.AppendHeader(table.Row{"Name/ID", "Field", "Current", "Planned"})
+	for _, diff := range plan.Diffs {
+		d.AppendRow(table.Row{diff.Name + "\n" + diff.ID, diff.Field, diff.Current, diff.Planned})
+	}
+	d.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true, VAlign: text.VAlignMiddle},
+	})
+	d.SetStyle(table.StyleLight)
+	d.Style().Options.SeparateRows = true
+	d.Render()
+}
+
+func mapToLines(m map[string]string) []string {
+	lines := []string{}
+	for k, v := range m {
+		lines = append(lines, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,73 @@
+package describe
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/projecteru2/cli/types"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/docker/go-units"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// ReallocResults describes resources of workloads before and after reallocating
+// output format can be json or yaml or table
+func ReallocResults(results ...*types.ReallocResult) {
+	switch {
+	case isJSON():
+		describeAsJSON(results)
+	case isYAML():
+		describeAsYAML(results)
+	default:
+		describeReallocResults(results)
+	}
+}
+
+func describeReallocResults(results []*types.ReallocResult) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Name/ID", "Node", "CPU Request/Limit", "Memory Request/Limit", "Storage Request/Limit", "Result"})
+	for _, r := range results {
+		result := "OK"
+		switch {
+		case r.Error != "":
+			result = r.Error
+		case r.DryRun:
+			result = "Fits"
+		}
+		t.AppendRow(table.Row{
+			fmt.Sprintf("%s\n%s", r.Name, coreutils.ShortID(r.ID)),
+			r.Nodename,
+			change(r.Before, r.After, func(w *types.WorkloadResources) string {
+				return strconv.FormatFloat(w.CPURequest, 'f', -1, 64) + "/" + strconv.FormatFloat(w.CPULimit, 'f', -1, 64)
+			}),
+			change(r.Before, r.After, func(w *types.WorkloadResources) string {
+				return units.BytesSize(float64(w.MemoryRequest)) + "/" + units.BytesSize(float64(w.MemoryLimit))
+			}),
+			change(r.Before, r.After, func(w *types.WorkloadResources) string {
+				return units.BytesSize(float64(w.StorageRequest)) + "/" + units.BytesSize(float64(w.StorageLimit))
+			}),
+			result,
+		})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
+
+// change shows a field before and after, or only before if it's not changed
+func change(before, after *types.WorkloadResources, field func(*types.WorkloadResources) string) string {
+	if before == nil {
+		return ""
+	}
+	b := field(before)
+	if after == nil {
+		return b
+	}
+	if a := field(after); a != b {
+		return b + " → " + a
+	}
+	return b
+}
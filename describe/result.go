@@ -0,0 +1,74 @@
+package describe
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+	coreutils "github.com/projecteru2/core/utils"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// DeployReport describes the report of deploying or replacing workloads
+// output format can be json or yaml or table
+func DeployReport(report *types.DeployReport) {
+	switch {
+	case isJSON():
+		describeAsJSON(report)
+	case isYAML():
+		describeAsYAML(report)
+	default:
+		describeWorkloadReports(report.Workloads)
+		if len(report.Entrypoints) > 0 {
+			describeEntrypointResults(report.Entrypoints)
+		}
+	}
+}
+
+func describeWorkloadReports(reports []*types.WorkloadReport) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Entrypoint", "Action", "ID", "Name", "Node", "Publish", "Result"})
+	for _, r := range reports {
+		publish := []string{}
+		for name, address := range r.Publish {
+			publish = append(publish, name+" "+address)
+		}
+		sort.Strings(publish)
+		result := "success"
+		if !r.Success {
+			result = "failed: " + r.Error
+		}
+		id := coreutils.ShortID(r.ID)
+		if r.ReplacedID != "" {
+			id = fmt.Sprintf("%s\n(replaced %s)", id, coreutils.ShortID(r.ReplacedID))
+		}
+		t.AppendRow(table.Row{r.Entrypoint, r.Action, id, r.Name, r.Nodename, strings.Join(publish, "\n"), result})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
+
+func describeEntrypointResults(results []*types.EntrypointResult) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Entrypoint", "Action", "Succeeded", "Failed", "Workloads", "Error"})
+	for _, r := range results {
+		action := r.Action
+		if r.Skipped {
+			action = "skipped"
+		}
+		ids := []string{}
+		for _, id := range r.IDs {
+			ids = append(ids, coreutils.ShortID(id))
+		}
+		t.AppendRow(table.Row{r.Entrypoint, action, r.Succeeded, r.Failed, strings.Join(ids, "\n"), r.Error})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
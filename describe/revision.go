@@ -0,0 +1,39 @@
+package describe
+
+import (
+	"os"
+	"strings"
+
+	"github.com/projecteru2/cli/types"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Revisions describes deploy revisions
+// output format can be json or yaml or table
+func Revisions(revisions ...*types.Revision) {
+	switch {
+	case isJSON():
+		describeAsJSON(revisions)
+	case isYAML():
+		describeAsYAML(revisions)
+	default:
+		describeRevisions(revisions)
+	}
+}
+
+func describeRevisions(revisions []*types.Revision) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Revision", "Deployed At", "Deployer", "Image", "Spec Hash", "CLI Version", "Entrypoints", "Count"})
+	for _, r := range revisions {
+		revision := r.Revision
+		if revision == "" {
+			revision = "-"
+		}
+		t.AppendRow(table.Row{revision, r.DeployedAt, r.Deployer, r.Image, r.SpecHash, r.CLIVersion, strings.Join(r.Entrypoints, "\n"), r.Count})
+		t.AppendSeparator()
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
@@ -25,6 +25,12 @@ func isYAML() bool {
 	return y == "yaml" || y == "yml"
 }
 
+// IsStructured returns if output format is json or yaml,
+// commands should not mix other things into stdout then
+func IsStructured() bool {
+	return isJSON() || isYAML()
+}
+
 // actually i need a `zip longest` function
 // like in python itertools
 func toTableRows(rows [][]string) []table.Row {
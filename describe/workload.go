@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/projecteru2/cli/types"
 	resourcetypes "github.com/projecteru2/core/resource/types"
 	corepb "github.com/projecteru2/core/rpc/gen"
 	coreutils "github.com/projecteru2/core/utils"
 
+	"github.com/docker/go-units"
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
@@ -28,53 +31,95 @@ func Workloads(workloads ...*corepb.Workload) {
 	}
 }
 
-// WorkloadsStatistics describes the statistics of the Workloads
-func WorkloadsStatistics(workloads ...*corepb.Workload) {
-	stat := struct {
+// WorkloadsStatistics describes the statistics of groups of Workloads,
+// groupBy is empty if not grouped, json or yaml is then in the shape before grouping was added,
+// which has only requests of cpu, memory and storage
+// output format can be json or yaml or table
+func WorkloadsStatistics(groupBy string, stats ...*types.WorkloadsStatistics) {
+	var v any = stats
+	if groupBy == "" {
+		v = totalStatistics(stats)
+	}
+	switch {
+	case isJSON():
+		describeAsJSON(v)
+	case isYAML():
+		describeAsYAML(v)
+	default:
+		describeWorkloadsStatistics(groupBy, stats)
+	}
+}
+
+// totalStatistics sums requests of cpu, memory and storage
+func totalStatistics(stats []*types.WorkloadsStatistics) any {
+	total := struct {
 		CPUs    float64
 		Memory  int64
 		Storage int64
 	}{}
-	for _, w := range workloads {
-		res := resourcetypes.Resources{}
-		if err := json.Unmarshal([]byte(w.Resources), &res); err != nil {
-			continue
-		}
-		cpu := res["cpumem"]["cpu_request"].(float64)
-		mem := res["cpumem"]["memory_request"].(float64)
-		if v, ok := res["storage"]; ok {
-			storage := v["storage_request"].(float64)
-			stat.Storage += int64(coreutils.Round(storage))
-		}
-		stat.CPUs += cpu
-		stat.Memory += int64(coreutils.Round(mem))
+	for _, stat := range stats {
+		total.CPUs += stat.Resources["cpumem"]["cpu_request"]
+		total.Memory += int64(coreutils.Round(stat.Resources["cpumem"]["memory_request"]))
+		total.Storage += int64(coreutils.Round(stat.Resources["storage"]["storage_request"]))
 	}
+	return total
+}
 
-	describeStatistics := func() {
-		t := table.NewWriter()
-		t.SetOutputMirror(os.Stdout)
-		t.AppendHeader(table.Row{"CPUs", "Memory", "Storage"})
+func describeWorkloadsStatistics(groupBy string, stats []*types.WorkloadsStatistics) {
+	plugins := []string{}
+	seen := map[string]bool{}
+	for _, stat := range stats {
+		for plugin := range stat.Resources {
+			if !seen[plugin] {
+				seen[plugin] = true
+				plugins = append(plugins, plugin)
+			}
+		}
+	}
+	sort.Strings(plugins)
 
-		rows := [][]string{
-			{fmt.Sprintf("%f", stat.CPUs)},
-			{fmt.Sprintf("%d", stat.Memory)},
-			{fmt.Sprintf("%d", stat.Storage)},
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{"Count"}
+	if groupBy != "" {
+		header = append(table.Row{groupBy}, header...)
+	}
+	for _, plugin := range plugins {
+		header = append(header, plugin)
+	}
+	t.AppendHeader(header)
+
+	for _, stat := range stats {
+		row := table.Row{stat.Count}
+		if groupBy != "" {
+			group := stat.Group
+			if group == "" {
+				group = "-"
+			}
+			row = append(table.Row{group}, row...)
 		}
-		t.AppendRows(toTableRows(rows))
+		for _, plugin := range plugins {
+			lines := []string{}
+			for param, total := range stat.Resources[plugin] {
+				lines = append(lines, fmt.Sprintf("%s: %s", param, formatTotal(param, total)))
+			}
+			sort.Strings(lines)
+			row = append(row, strings.Join(lines, "\n"))
+		}
+		t.AppendRow(row)
 		t.AppendSeparator()
-
-		t.SetStyle(table.StyleLight)
-		t.Render()
 	}
 
-	switch {
-	case isJSON():
-		describeAsJSON(stat)
-	case isYAML():
-		describeAsYAML(stat)
-	default:
-		describeStatistics()
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
+
+// formatTotal shows totals of memory and storage in human readable size
+func formatTotal(param string, total float64) string {
+	if strings.Contains(param, "memory") || strings.Contains(param, "storage") {
+		return units.BytesSize(total)
 	}
+	return strconv.FormatFloat(total, 'f', -1, 64)
 }
 
 func describeWorkloads(workloads []*corepb.Workload) {
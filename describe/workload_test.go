@@ -0,0 +1,31 @@
+package describe
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/projecteru2/cli/types"
+)
+
+// totals without groups are kept in the shape before grouping, scripts may depend on it
+func TestTotalStatistics(t *testing.T) {
+	stats := []*types.WorkloadsStatistics{{
+		Count: 2,
+		Resources: map[string]map[string]float64{
+			"cpumem":  {"cpu_request": 1.5, "cpu_limit": 2, "memory_request": 2048},
+			"storage": {"storage_request": 10},
+		},
+	}}
+	b, err := json.Marshal(totalStatistics(stats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"CPUs":1.5,"Memory":2048,"Storage":10}`; string(b) != expected {
+		t.Errorf("totalStatistics() = %s, want %s", b, expected)
+	}
+
+	b, _ = json.Marshal(totalStatistics(nil))
+	if expected := `{"CPUs":0,"Memory":0,"Storage":0}`; string(b) != expected {
+		t.Errorf("totalStatistics(nil) = %s, want %s", b, expected)
+	}
+}
@@ -0,0 +1,178 @@
+package interactive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// CastHeader is the header line of an asciicast v2 recording
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder records a session in asciicast v2 format,
+// with output, input and resize events
+type Recorder struct {
+	sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	start   time.Time
+	partial []byte
+	err     error
+}
+
+// NewRecorder creates a recording file at path,
+// if path is a directory, the file is named by the current time inside it,
+// existing recordings are never overwritten
+func NewRecorder(path, title, command string) (*Recorder, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		path = filepath.Join(path, fmt.Sprintf("%s-%d.cast", time.Now().Format("20060102-150405"), os.Getpid()))
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	width, height := terminalSize()
+	header := &CastHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: start.Unix(),
+		Command:   command,
+		Title:     title,
+		Env: map[string]string{
+			"TERM":  os.Getenv("TERM"),
+			"SHELL": os.Getenv("SHELL"),
+			"USER":  os.Getenv("USER"),
+		},
+	}
+	r := &Recorder{file: file, w: bufio.NewWriter(file), start: start}
+	r.write(header)
+	if r.err != nil {
+		_ = file.Close()
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// Path returns path of the recording file
+func (r *Recorder) Path() string {
+	return r.file.Name()
+}
+
+// Output records output of the session,
+// a rune split across outputs is recorded as a whole with the later one
+func (r *Recorder) Output(data []byte) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	data = append(r.partial, data...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.partial = append([]byte{}, data[cut:]...)
+	r.Unlock()
+
+	if cut > 0 {
+		r.event("o", string(data[:cut]))
+	}
+}
+
+// Input records input of the session
+func (r *Recorder) Input(data []byte) {
+	r.event("i", string(data))
+}
+
+// Resize records terminal resizing of the session
+func (r *Recorder) Resize(width, height int) {
+	r.event("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close flushes and closes the recording file
+func (r *Recorder) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.partial) > 0 {
+		r.write([]any{time.Since(r.start).Seconds(), "o", string(r.partial)})
+	}
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// command records a command sent to the session,
+// resize commands are recorded as resize events, escape commands are skipped
+func (r *Recorder) command(cmd []byte) {
+	switch {
+	case bytes.HasPrefix(cmd, winchCommand):
+		w := &window{}
+		if err := json.Unmarshal(cmd[len(winchCommand):], w); err == nil {
+			r.Resize(int(w.Col), int(w.Row))
+		}
+	case bytes.Equal(cmd, escapeCommand):
+	default:
+		r.Input(cmd)
+	}
+}
+
+func (r *Recorder) event(kind, data string) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.write([]any{time.Since(r.start).Seconds(), kind, data})
+	// flush every event, so the recording survives the process being killed
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+}
+
+func (r *Recorder) write(v any) {
+	if r.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.err = err
+		return
+	}
+	_, r.err = r.w.Write(append(b, '\n'))
+}
+
+// terminalSize returns size of the terminal of stdin, 80x24 if it's not a terminal
+func terminalSize() (int, int) {
+	w := &window{}
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(w))); err != 0 || w.Col == 0 {
+		return 80, 24
+	}
+	return int(w.Col), int(w.Row)
+}
@@ -1,14 +1,15 @@
 package interactive
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"text/template"
 	"unsafe"
@@ -21,11 +22,19 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// DetachKeys is the key sequence to detach from an interactive session,
+// a sequence starting with "~" only takes effect at the beginning of a line, as ssh does
+var DetachKeys = "~."
+
 var (
-	exitCode     = []byte{91, 101, 120, 105, 116, 99, 111, 100, 101, 93, 32}
-	winchCommand = []byte{0x80}
+	exitCode      = []byte{91, 101, 120, 105, 116, 99, 111, 100, 101, 93, 32}
+	winchCommand  = []byte{0x80}
+	escapeCommand = []byte{0x1d}
+	eofCharacter  = byte(0x04)
 )
 
+const readBufferSize = 32 * 1024
+
 type window struct {
 	Row    uint16
 	Col    uint16
@@ -33,96 +42,59 @@ type window struct {
 	Ypixel uint16 `json:"-"`
 }
 
-// Stream is a wrapper for send and recv method
+// Stream is a wrapper for send and recv method,
+// the session is recorded if Recorder is set
 type Stream struct {
-	Send func(cmd []byte) error
-	Recv func() (*corepb.AttachWorkloadMessage, error)
+	Send     func(cmd []byte) error
+	Recv     func() (*corepb.AttachWorkloadMessage, error)
+	Recorder *Recorder
+}
+
+type result struct {
+	code int
+	err  error
 }
 
 // HandleStream will handle a stream with send and recv method
-// with or without interactive mode
+// with or without interactive mode,
+// stdin is forwarded in raw mode when it's a terminal, or in chunks when it's not
 func HandleStream(interactive bool, iStream Stream, exitCount int, printWorkloadID bool) (code int, err error) {
-	if interactive { //nolint
+	if r := iStream.Recorder; r != nil {
+		send := iStream.Send
+		iStream.Send = func(cmd []byte) error {
+			r.command(cmd)
+			return send(cmd)
+		}
+	}
+
+	detached := make(chan struct{})
+	aborted := make(chan error, 1)
+	if interactive {
 		stdinFd := os.Stdin.Fd()
 		terminal := &unix.Termios{}
-		_ = termios.Tcgetattr(stdinFd, terminal)
-		terminalBak := &unix.Termios{}
-		_ = deepcopy.Copy(terminalBak, terminal)
-		defer func() { _ = termios.Tcsetattr(stdinFd, termios.TCSANOW, terminalBak) }()
-
-		terminal.Lflag &^= syscall.ECHO   // off echoing
-		terminal.Lflag &^= syscall.ICANON // noncanonical mode
-		terminal.Lflag &^= syscall.ISIG   // disable signals
-		terminal.Lflag &^= syscall.IEXTEN // extended input processing
-
-		terminal.Iflag &^= syscall.BRKINT // disable special handling of BREAK
-		terminal.Iflag &^= syscall.ICRNL  // disable special handling of CR
-		terminal.Iflag &^= syscall.IGNBRK // disable special handling of BREAK
-		terminal.Iflag &^= syscall.IGNCR  // disable special handling of CR
-		terminal.Iflag &^= syscall.INLCR  // disable special handling of NL
-		terminal.Iflag &^= syscall.INPCK  // no parity error handling
-		terminal.Iflag &^= syscall.ISTRIP // no 8th-bit stripping
-		terminal.Iflag &^= syscall.IXON   // disable output flow control
-		terminal.Iflag &^= syscall.PARMRK // no parity error handling
-
-		terminal.Oflag &^= syscall.OPOST // disable all output processing
-
-		terminal.Cc[syscall.VMIN] = 1  // character-at-a-time input
-		terminal.Cc[syscall.VTIME] = 0 // blocking
-
-		_ = termios.Tcsetattr(stdinFd, termios.TCSAFLUSH, terminal)
-
-		// capture SIGWINCH and measure window size
-		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, syscall.SIGWINCH)
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-		resize := func(_ context.Context) error {
-			w := &window{}
-			if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, stdinFd, syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(w))); err != 0 {
-				return err
-			}
-			opts, err := json.Marshal(w)
+		if termios.Tcgetattr(stdinFd, terminal) == nil {
+			keys, err := parseDetachKeys(DetachKeys)
 			if err != nil {
-				return err
+				return -1, err
 			}
-			command := append(winchCommand, opts...) //nolint
-			return iStream.Send(command)
-		}
+			restore := makeRaw(stdinFd, terminal)
+			defer restore()
 
-		go func(ctx context.Context) {
-			for {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go watchWindow(ctx, stdinFd, iStream)
+			go forwardTerminal(iStream, keys, detached)
+			defer func() {
 				select {
-				case <-ctx.Done():
-					break
-				case _, ok := <-sigs:
-					if !ok {
-						return
-					}
-					if err := resize(ctx); err != nil {
-						logrus.Errorf("[HandleStream] Resize error: %v", err)
-					}
-				}
-			}
-		}(ctx)
-
-		go func() {
-			if err := resize(ctx); err != nil {
-				logrus.Errorf("[HandleStream] Resize error: %v", err)
-			}
-			scanner := bufio.NewScanner(os.Stdin)
-			scanner.Split(bufio.ScanRunes)
-			for scanner.Scan() {
-				b := scanner.Bytes()
-				if err := iStream.Send(b); err != nil {
-					logrus.Errorf("[HandleStream] Send command %s error: %v", b, err)
+				case <-detached:
+					restore()
+					logrus.Infof("[HandleStream] Detached from session")
+				default:
 				}
-			}
-			if err := scanner.Err(); err != nil {
-				logrus.Errorf("[HandleStream] Failed to read output from virtual unit: %v", err)
-				return
-			}
-		}()
+			}()
+		} else {
+			go forwardPipe(iStream, aborted)
+		}
 	}
 
 	outputTemplate := `{{printf "%s" .Data}}`
@@ -135,7 +107,25 @@ func HandleStream(interactive bool, iStream Stream, exitCount int, printWorkload
 		return -1, err
 	}
 
+	done := make(chan result, 1)
+	go func() {
+		code, err := receive(iStream, outputT, exitCount)
+		done <- result{code, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.code, r.err
+	case <-detached:
+		return 0, nil
+	case err := <-aborted:
+		return -1, err
+	}
+}
+
+func receive(iStream Stream, outputT *template.Template, exitCount int) (code int, err error) {
 	exited := 0
+	output := &bytes.Buffer{}
 	for {
 		msg, err := iStream.Recv()
 		if err == io.EOF {
@@ -176,10 +166,260 @@ func HandleStream(interactive bool, iStream Stream, exitCount int, printWorkload
 		default:
 			outStream = os.Stderr
 		}
-		if err := outputT.Execute(outStream, msg); err != nil {
+		output.Reset()
+		if err := outputT.Execute(output, msg); err != nil {
 			logrus.Errorf("[HandleStream] Render template error: %v", err)
+			continue
 		}
+		iStream.Recorder.Output(output.Bytes())
+		_, _ = outStream.Write(output.Bytes())
 	}
 
 	return code, err
 }
+
+// makeRaw puts terminal into raw mode, returns a function to restore it
+func makeRaw(stdinFd uintptr, terminal *unix.Termios) func() {
+	terminalBak := &unix.Termios{}
+	_ = deepcopy.Copy(terminalBak, terminal)
+
+	terminal.Lflag &^= syscall.ECHO   // off echoing
+	terminal.Lflag &^= syscall.ICANON // noncanonical mode
+	terminal.Lflag &^= syscall.ISIG   // disable signals
+	terminal.Lflag &^= syscall.IEXTEN // extended input processing
+
+	terminal.Iflag &^= syscall.BRKINT // disable special handling of BREAK
+	terminal.Iflag &^= syscall.ICRNL  // disable special handling of CR
+	terminal.Iflag &^= syscall.IGNBRK // disable special handling of BREAK
+	terminal.Iflag &^= syscall.IGNCR  // disable special handling of CR
+	terminal.Iflag &^= syscall.INLCR  // disable special handling of NL
+	terminal.Iflag &^= syscall.INPCK  // no parity error handling
+	terminal.Iflag &^= syscall.ISTRIP // no 8th-bit stripping
+	terminal.Iflag &^= syscall.IXON   // disable output flow control
+	terminal.Iflag &^= syscall.PARMRK // no parity error handling
+
+	terminal.Oflag &^= syscall.OPOST // disable all output processing
+
+	terminal.Cc[syscall.VMIN] = 1  // character-at-a-time input
+	terminal.Cc[syscall.VTIME] = 0 // blocking
+
+	_ = termios.Tcsetattr(stdinFd, termios.TCSAFLUSH, terminal)
+	return func() { _ = termios.Tcsetattr(stdinFd, termios.TCSANOW, terminalBak) }
+}
+
+// watchWindow captures SIGWINCH and sends window size to remote
+func watchWindow(ctx context.Context, stdinFd uintptr, iStream Stream) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	defer signal.Stop(sigs)
+
+	resize := func() error {
+		w := &window{}
+		if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, stdinFd, syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(w))); err != 0 {
+			return err
+		}
+		opts, err := json.Marshal(w)
+		if err != nil {
+			return err
+		}
+		command := append(winchCommand, opts...) //nolint
+		return iStream.Send(command)
+	}
+
+	if err := resize(); err != nil {
+		logrus.Errorf("[HandleStream] Resize error: %v", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+			if err := resize(); err != nil {
+				logrus.Errorf("[HandleStream] Resize error: %v", err)
+			}
+		}
+	}
+}
+
+// forwardTerminal sends keys typed in terminal to remote,
+// detached will be closed once detach keys are typed
+func forwardTerminal(iStream Stream, keys []byte, detached chan struct{}) {
+	d := &detacher{keys: keys, lineStart: true}
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			data, detach := d.scan(buf[:n])
+			if len(data) > 0 {
+				if err := iStream.Send(data); err != nil {
+					logrus.Errorf("[HandleStream] Send command %q error: %v", data, err)
+				}
+			}
+			if detach {
+				close(detached)
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				logrus.Errorf("[HandleStream] Failed to read from stdin: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// forwardPipe sends stdin to remote in chunks, and closes remote stdin on EOF.
+// Remote stdin is a terminal, which handles control characters and echoes input,
+// so it's not binary safe. The session is aborted by an error to aborted if input can't be sent as it is
+func forwardPipe(iStream Stream, aborted chan<- error) {
+	s := &pipeSender{send: iStream.Send}
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			if err := s.write(buf[:n]); err != nil {
+				aborted <- fmt.Errorf("[HandleStream] send stdin failed %v", err)
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				logrus.Errorf("[HandleStream] Failed to read from stdin: %v", err)
+			}
+			break
+		}
+	}
+	if err := s.close(); err != nil {
+		logrus.Errorf("[HandleStream] Close stdin error: %v", err)
+	}
+}
+
+// pipeSender splits input into messages never starting with a command byte,
+// since core takes such messages as commands rather than input.
+// Input starting with a command byte can't be sent as it is, it's refused
+type pipeSender struct {
+	send    func([]byte) error
+	pending []byte
+	last    byte
+	sent    bool
+}
+
+func isCommandByte(b byte) bool {
+	return b == winchCommand[0] || b == escapeCommand[0]
+}
+
+func (s *pipeSender) write(b []byte) error {
+	s.pending = append(s.pending, b...)
+	if !s.sent && len(s.pending) > 0 && isCommandByte(s.pending[0]) {
+		return fmt.Errorf("stdin starts with byte %#x, which eru-core takes as a command, send files by `workload cp` or `workload send` instead", s.pending[0])
+	}
+	// hold the last ordinary byte and what follows,
+	// so the next message is able to start with it
+	k := len(s.pending) - 1
+	for k > 0 && isCommandByte(s.pending[k]) {
+		k--
+	}
+	if k <= 0 {
+		return nil
+	}
+	if err := s.flush(s.pending[:k]); err != nil {
+		return err
+	}
+	s.pending = s.pending[:copy(s.pending, s.pending[k:])]
+	return nil
+}
+
+func (s *pipeSender) flush(b []byte) error {
+	s.last, s.sent = b[len(b)-1], true
+	return s.send(b)
+}
+
+func (s *pipeSender) close() error {
+	if len(s.pending) > 0 {
+		if err := s.flush(s.pending); err != nil {
+			return err
+		}
+	}
+	// remote stdin is a terminal, send EOF as a terminal does,
+	// twice if the last line is not terminated
+	eof := []byte{eofCharacter}
+	if s.sent && s.last != '\n' {
+		eof = append(eof, eofCharacter)
+	}
+	if err := s.send(eof); err != nil {
+		return err
+	}
+	return s.send(escapeCommand)
+}
+
+// detacher looks for detach keys in terminal input
+type detacher struct {
+	keys      []byte
+	matched   int
+	lineStart bool
+}
+
+// scan returns input to forward, with detach keys held until they're mismatched,
+// and whether the whole detach keys are typed
+func (d *detacher) scan(input []byte) ([]byte, bool) {
+	if len(d.keys) == 0 {
+		return input, false
+	}
+	out := make([]byte, 0, len(input)+d.matched)
+	for _, b := range input {
+		if d.matched > 0 && b == d.keys[d.matched] {
+			d.matched++
+			if d.matched == len(d.keys) {
+				return out, true
+			}
+			continue
+		}
+		if d.matched > 0 {
+			// typing the first key twice sends it once, as ssh does
+			twice := d.matched == 1 && b == d.keys[0]
+			out = append(out, d.keys[:d.matched]...)
+			d.matched, d.lineStart = 0, false
+			if twice {
+				continue
+			}
+		}
+		if b == d.keys[0] && (d.lineStart || d.keys[0] != '~') {
+			d.matched = 1
+			if len(d.keys) == 1 {
+				return out, true
+			}
+			continue
+		}
+		out = append(out, b)
+		d.lineStart = b == '\r' || b == '\n'
+	}
+	return out, false
+}
+
+// parseDetachKeys parses detach keys, either literal keys like "~.",
+// or comma separated keys like "ctrl-p,ctrl-q", empty keys disable detaching
+func parseDetachKeys(s string) ([]byte, error) {
+	if len(s) < 2 || (!strings.Contains(s, ",") && !strings.HasPrefix(s, "ctrl-")) {
+		return []byte(s), nil
+	}
+	keys := []byte{}
+	for _, key := range strings.Split(s, ",") {
+		switch {
+		case len(key) == 1:
+			keys = append(keys, key[0])
+		case len(key) == 6 && strings.HasPrefix(key, "ctrl-"):
+			c := key[5]
+			if c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			if c < '@' || c > '_' {
+				return nil, fmt.Errorf("[HandleStream] invalid detach key %s", key)
+			}
+			keys = append(keys, c-'@')
+		default:
+			return nil, fmt.Errorf("[HandleStream] invalid detach key %s", key)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,98 @@
+package interactive
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPipeSender(t *testing.T) {
+	cases := []struct {
+		name     string
+		chunks   [][]byte
+		expected []byte
+	}{
+		{"plain", [][]byte{[]byte("ab"), []byte("cd\n")}, []byte("abcd\n")},
+		{"end with command bytes", [][]byte{{'a', 'b', 0x80}, {0x1d, 'c'}}, []byte{'a', 'b', 0x80, 0x1d, 'c'}},
+		{"chunk starts with command bytes", [][]byte{[]byte("ab"), {0x80, 'c'}, {0x1d}}, []byte{'a', 'b', 0x80, 'c', 0x1d}},
+		{"single bytes", [][]byte{{'a'}, {0x80}, {'b'}, {0x1d}}, []byte{'a', 0x80, 'b', 0x1d}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msgs := [][]byte{}
+			s := &pipeSender{send: func(b []byte) error {
+				msgs = append(msgs, append([]byte{}, b...))
+				return nil
+			}}
+			for _, chunk := range c.chunks {
+				if err := s.write(chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// EOF characters and close stdin command are sent at last
+			if len(msgs) < 2 || !bytes.Equal(msgs[len(msgs)-1], escapeCommand) {
+				t.Fatalf("last message is not close stdin: %q", msgs)
+			}
+			eof := msgs[len(msgs)-2]
+			input := []byte{}
+			for _, msg := range msgs[:len(msgs)-2] {
+				if len(msg) == 0 || isCommandByte(msg[0]) {
+					t.Errorf("message %q starts with a command byte", msg)
+				}
+				input = append(input, msg...)
+			}
+			if !bytes.Equal(input, c.expected) {
+				t.Errorf("input = %q, want %q", input, c.expected)
+			}
+
+			expectedEOF := []byte{eofCharacter, eofCharacter}
+			if len(c.expected) == 0 || c.expected[len(c.expected)-1] == '\n' {
+				expectedEOF = expectedEOF[:1]
+			}
+			if !bytes.Equal(eof, expectedEOF) {
+				t.Errorf("eof = %q, want %q", eof, expectedEOF)
+			}
+		})
+	}
+}
+
+func TestPipeSenderRefusesCommandBytes(t *testing.T) {
+	for _, chunks := range [][][]byte{
+		{{0x80, 'a', 'b'}},
+		{{0x1d}},
+		{{}, {0x80}, []byte("ab")},
+	} {
+		sent := 0
+		s := &pipeSender{send: func(b []byte) error {
+			sent++
+			return nil
+		}}
+		var err error
+		for _, chunk := range chunks {
+			if err = s.write(chunk); err != nil {
+				break
+			}
+		}
+		if err == nil || sent > 0 {
+			t.Errorf("write(%q) = %v, sent %d message(s), should be refused", chunks, err, sent)
+		}
+	}
+}
+
+func TestDetacher(t *testing.T) {
+	d := &detacher{keys: []byte("~."), lineStart: true}
+	out, detach := d.scan([]byte("a~b\n"))
+	if string(out) != "a~b\n" || detach {
+		t.Errorf("scan() = %q, %v", out, detach)
+	}
+	out, detach = d.scan([]byte("~"))
+	if len(out) != 0 || detach {
+		t.Errorf("scan() holds keys, got %q, %v", out, detach)
+	}
+	if _, detach = d.scan([]byte(".")); !detach {
+		t.Error("scan() should detach")
+	}
+}
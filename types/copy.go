@@ -0,0 +1,15 @@
+package types
+
+// CopiedFile is a file copied from a workload
+type CopiedFile struct {
+	Source string `json:"source"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+}
+
+// CopyManifest lists files copied from a workload and errors during copying
+type CopyManifest struct {
+	ID     string        `json:"id"`
+	Files  []*CopiedFile `json:"files"`
+	Errors []string      `json:"errors,omitempty"`
+}
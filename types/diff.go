@@ -0,0 +1,21 @@
+package types
+
+// FieldDiff is a field differing between two workloads, in lines
+type FieldDiff struct {
+	Field string   `json:"field"`
+	A     []string `json:"a"`
+	B     []string `json:"b"`
+}
+
+// WorkloadsDiff is the differences between two workloads
+type WorkloadsDiff struct {
+	A      *DiffedWorkload `json:"a"`
+	B      *DiffedWorkload `json:"b"`
+	Fields []*FieldDiff    `json:"fields"`
+}
+
+// DiffedWorkload identifies a workload being diffed
+type DiffedWorkload struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,23 @@
+package types
+
+// DrainGroup is workloads of an entrypoint on the drained node,
+// Capacity is how many of them other nodes can hold, checked alone without workloads of other entrypoints
+type DrainGroup struct {
+	Appname    string `json:"appname"`
+	Entrypoint string `json:"entrypoint"`
+	Count      int    `json:"count"`
+	Capacity   int64  `json:"capacity"`
+	Fits       bool   `json:"fits"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DrainResult is the result of migrating a workload off the drained node
+type DrainResult struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	NewID       string `json:"new_id,omitempty"`
+	NewName     string `json:"new_name,omitempty"`
+	NewNodename string `json:"new_nodename,omitempty"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+}
@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// kinds of status events
+const (
+	StatusEventHealthy   = "healthy"
+	StatusEventUnhealthy = "unhealthy"
+	StatusEventStopped   = "stopped"
+	StatusEventExpired   = "expired"
+	StatusEventDeleted   = "deleted"
+	StatusEventError     = "error"
+)
+
+// StatusEvent is a status change of a workload, sent to sinks of status command
+type StatusEvent struct {
+	Time     time.Time         `json:"time"`
+	Kind     string            `json:"kind"`
+	ID       string            `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	Podname  string            `json:"podname,omitempty"`
+	Nodename string            `json:"nodename,omitempty"`
+	Running  bool              `json:"running"`
+	Healthy  bool              `json:"healthy"`
+	Publish  map[string]string `json:"publish,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
@@ -0,0 +1,27 @@
+package types
+
+// ExecResult is the result of executing commands in a workload
+type ExecResult struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Nodename   string `json:"nodename"`
+	ExitCode   int    `json:"exit_code"`
+	OutputHash string `json:"output_hash"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ExecGroup is a group of workloads exiting with the same code and output
+type ExecGroup struct {
+	ExitCode   int      `json:"exit_code"`
+	OutputHash string   `json:"output_hash"`
+	Error      string   `json:"error,omitempty"`
+	Workloads  []string `json:"workloads"`
+}
+
+// ExecSummary is the summary of executing commands in workloads
+type ExecSummary struct {
+	Groups  []*ExecGroup  `json:"groups"`
+	Results []*ExecResult `json:"results"`
+}
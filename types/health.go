@@ -0,0 +1,34 @@
+package types
+
+// WorkloadHealth is the health of a workload reported by its status
+type WorkloadHealth struct {
+	ID       string `json:"id"`
+	Reported bool   `json:"reported"`
+	Running  bool   `json:"running"`
+	Healthy  bool   `json:"healthy"`
+	Deleted  bool   `json:"deleted,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// OK returns if the workload is running and healthy
+func (h *WorkloadHealth) OK() bool {
+	return h.Running && h.Healthy && !h.Deleted
+}
+
+// Reason explains why the workload is not OK
+func (h *WorkloadHealth) Reason() string {
+	switch {
+	case h.Error != "":
+		return h.Error
+	case h.Deleted:
+		return "workload or its status is removed"
+	case !h.Reported:
+		return "no status reported, agent may be down or workload not started"
+	case !h.Running:
+		return "not running"
+	case !h.Healthy:
+		return "running but not healthy, healthcheck is not passed"
+	default:
+		return "running and healthy"
+	}
+}
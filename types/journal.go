@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+
+	corepb "github.com/projecteru2/core/rpc/gen"
+)
+
+// Journal records a replace, so it can be rolled back,
+// files shipped are not kept in Options, Files maps their names to sha256 of their contents instead
+type Journal struct {
+	ID         string                 `json:"id"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Appname    string                 `json:"appname"`
+	Entrypoint string                 `json:"entrypoint"`
+	Image      string                 `json:"image"`
+	RollbackOf string                 `json:"rollback_of,omitempty"`
+	Options    *corepb.ReplaceOptions `json:"options"`
+	Files      map[string]string      `json:"files,omitempty"`
+	Entries    []*JournalEntry        `json:"entries"`
+}
+
+// JournalEntry records an old workload and the new workload replacing it,
+// NewID is empty if it's not replaced yet or failed
+type JournalEntry struct {
+	OldID      string            `json:"old_id"`
+	OldName    string            `json:"old_name"`
+	Nodename   string            `json:"nodename"`
+	Privileged bool              `json:"privileged,omitempty"`
+	Image      string            `json:"image"`
+	Env        []string          `json:"env,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Resources  string            `json:"resources,omitempty"`
+	NewID      string            `json:"new_id,omitempty"`
+	NewName    string            `json:"new_name,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Replaced returns entries replaced successfully
+func (j *Journal) Replaced() []*JournalEntry {
+	entries := []*JournalEntry{}
+	for _, entry := range j.Entries {
+		if entry.NewID != "" && entry.Error == "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
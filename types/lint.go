@@ -0,0 +1,17 @@
+package types
+
+const (
+	// LintError means the spec will be rejected or silently misbehave
+	LintError = "error"
+	// LintWarning means the spec works but is probably not what you want
+	LintWarning = "warning"
+)
+
+// LintIssue is a problem found in a spec file
+type LintIssue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,86 @@
+package types
+
+import "time"
+
+// Maintenance steps, in order of the runbook,
+// steps before MaintenanceWork are run by start, the rest are run by finish
+const (
+	MaintenanceCordon    = "cordon"
+	MaintenanceCritical  = "check-critical"
+	MaintenanceDrain     = "drain"
+	MaintenanceWait      = "wait-empty"
+	MaintenanceResource  = "check-resource"
+	MaintenanceWork      = "maintenance"
+	MaintenanceUp        = "up"
+	MaintenanceHeartbeat = "verify-heartbeat"
+	MaintenanceUncordon  = "uncordon"
+)
+
+// MaintenanceSteps are all steps of a maintenance, in order
+var MaintenanceSteps = []string{
+	MaintenanceCordon,
+	MaintenanceCritical,
+	MaintenanceDrain,
+	MaintenanceWait,
+	MaintenanceResource,
+	MaintenanceWork,
+	MaintenanceUp,
+	MaintenanceHeartbeat,
+	MaintenanceUncordon,
+}
+
+// Maintenance records progress of maintaining a node, so it can be resumed
+type Maintenance struct {
+	Nodename  string             `json:"nodename"`
+	StartedAt time.Time          `json:"started_at"`
+	Operator  string             `json:"operator"`
+	Steps     []*MaintenanceStep `json:"steps"`
+	Diffs     []string           `json:"diffs,omitempty"`
+
+	// Bypass is the bypass of the node before the maintenance, restored by uncordon
+	Bypass bool `json:"bypass"`
+}
+
+// MaintenanceStep is a step of a maintenance,
+// Error is the error of the last try, cleared once the step is done
+type MaintenanceStep struct {
+	Name   string    `json:"name"`
+	Done   bool      `json:"done"`
+	DoneAt time.Time `json:"done_at,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// NewMaintenance returns a maintenance of the node with all steps not done,
+// bypass is the bypass of the node now, restored after the maintenance
+func NewMaintenance(nodename, operator string, bypass bool) *Maintenance {
+	m := &Maintenance{
+		Nodename:  nodename,
+		StartedAt: time.Now(),
+		Operator:  operator,
+		Bypass:    bypass,
+	}
+	for _, name := range MaintenanceSteps {
+		m.Steps = append(m.Steps, &MaintenanceStep{Name: name})
+	}
+	return m
+}
+
+// Step returns the step by name, nil if not found
+func (m *Maintenance) Step(name string) *MaintenanceStep {
+	for _, s := range m.Steps {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Next returns the first step not done, nil if all are done
+func (m *Maintenance) Next() *MaintenanceStep {
+	for _, s := range m.Steps {
+		if !s.Done {
+			return s
+		}
+	}
+	return nil
+}
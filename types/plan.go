@@ -0,0 +1,55 @@
+package types
+
+import (
+	corepb "github.com/projecteru2/core/rpc/gen"
+)
+
+// DeployPlan is the fully resolved deploy options,
+// along with what would change on existing workloads
+type DeployPlan struct {
+	Appname     string                     `json:"appname"`
+	Entrypoint  string                     `json:"entrypoint"`
+	Podname     string                     `json:"podname"`
+	Nodes       []string                   `json:"nodes,omitempty"`
+	NodeLabels  map[string]string          `json:"node_labels,omitempty"`
+	Image       string                     `json:"image"`
+	Count       int32                      `json:"count"`
+	Strategy    string                     `json:"strategy"`
+	User        string                     `json:"user"`
+	Commands    []string                   `json:"commands"`
+	Dir         string                     `json:"dir,omitempty"`
+	Privileged  bool                       `json:"privileged"`
+	Restart     string                     `json:"restart,omitempty"`
+	Env         []string                   `json:"env,omitempty"`
+	Networks    map[string]string          `json:"networks,omitempty"`
+	Publish     []string                   `json:"publish,omitempty"`
+	Labels      map[string]string          `json:"labels,omitempty"`
+	Resources   map[string]any             `json:"resources"`
+	Files       []*PlanFile                `json:"files,omitempty"`
+	Hook        *corepb.HookOptions        `json:"hook,omitempty"`
+	HealthCheck *corepb.HealthCheckOptions `json:"healthcheck,omitempty"`
+	AfterCreate []string                   `json:"after_create,omitempty"`
+	// Existing is how many workloads of this app/entry are running
+	Existing int         `json:"existing"`
+	Diffs    []*PlanDiff `json:"diffs,omitempty"`
+}
+
+// PlanFile describes a file sent to workloads,
+// content is replaced by its checksum
+type PlanFile struct {
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+	Mode   string `json:"mode,omitempty"`
+	UID    int32  `json:"uid"`
+	GID    int32  `json:"gid"`
+}
+
+// PlanDiff is a difference between an existing workload and the plan
+type PlanDiff struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	Planned string `json:"planned"`
+}
@@ -0,0 +1,15 @@
+package types
+
+// ReallocResult is the result of reallocating resources of a workload,
+// After is predicted from the increments with dry run
+type ReallocResult struct {
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	Podname  string             `json:"podname"`
+	Nodename string             `json:"nodename"`
+	Before   *WorkloadResources `json:"before"`
+	After    *WorkloadResources `json:"after"`
+	DryRun   bool               `json:"dry_run,omitempty"`
+	Success  bool               `json:"success"`
+	Error    string             `json:"error,omitempty"`
+}
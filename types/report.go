@@ -0,0 +1,34 @@
+package types
+
+// WorkloadReport is the result of creating or replacing a workload
+type WorkloadReport struct {
+	Entrypoint string            `json:"entrypoint"`
+	Action     string            `json:"action"`
+	ID         string            `json:"id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Nodename   string            `json:"nodename,omitempty"`
+	Publish    map[string]string `json:"publish,omitempty"`
+	ReplacedID string            `json:"replaced_id,omitempty"`
+	Hook       string            `json:"hook,omitempty"`
+	StopHook   string            `json:"stop_hook,omitempty"`
+	Success    bool              `json:"success"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// DeployReport is the report of deploying or replacing workloads,
+// entrypoints are only reported when deploying multiple entrypoints
+type DeployReport struct {
+	Workloads   []*WorkloadReport   `json:"workloads"`
+	Entrypoints []*EntrypointResult `json:"entrypoints,omitempty"`
+}
+
+// Failed returns how many workloads failed
+func (r *DeployReport) Failed() int {
+	failed := 0
+	for _, w := range r.Workloads {
+		if !w.Success {
+			failed++
+		}
+	}
+	return failed
+}
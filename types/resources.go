@@ -0,0 +1,140 @@
+package types
+
+import (
+	"encoding/json"
+
+	resourcetypes "github.com/projecteru2/core/resource/types"
+)
+
+// WorkloadResources is a flattened view of cpumem and storage resources,
+// used to compare resources of running workloads with requested ones
+type WorkloadResources struct {
+	CPURequest     float64  `json:"cpu_request"`
+	CPULimit       float64  `json:"cpu_limit"`
+	CPUBind        bool     `json:"cpu_bind"`
+	MemoryRequest  int64    `json:"memory_request"`
+	MemoryLimit    int64    `json:"memory_limit"`
+	StorageRequest int64    `json:"storage_request"`
+	StorageLimit   int64    `json:"storage_limit"`
+	VolumesRequest []string `json:"volumes_request,omitempty"`
+	VolumesLimit   []string `json:"volumes_limit,omitempty"`
+}
+
+// ParseWorkloadResources parses resources of a workload,
+// which is a json string like {"cpumem": {"cpu_request": 1}, "storage": {...}}
+func ParseWorkloadResources(resources string) (*WorkloadResources, error) {
+	res := resourcetypes.Resources{}
+	if resources != "" {
+		if err := json.Unmarshal([]byte(resources), &res); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &WorkloadResources{}
+	if cpumem := res["cpumem"]; cpumem != nil {
+		r.CPURequest = cpumem.Float64("cpu_request")
+		r.CPULimit = cpumem.Float64("cpu_limit")
+		r.MemoryRequest = cpumem.Int64("memory_request")
+		r.MemoryLimit = cpumem.Int64("memory_limit")
+		r.CPUBind = len(cpumem.RawParams("cpu_map")) > 0
+	}
+	if storage := res["storage"]; storage != nil {
+		r.StorageRequest = storage.Int64("storage_request")
+		r.StorageLimit = storage.Int64("storage_limit")
+		r.VolumesRequest = storage.StringSlice("volumes_request")
+		r.VolumesLimit = storage.StringSlice("volumes_limit")
+	}
+	return r, nil
+}
+
+// ParseDeployResources parses resources given in DeployOptions,
+// which is a map of plugin name to json params like {"cpu-request": 1}
+func ParseDeployResources(resources map[string][]byte) (*WorkloadResources, error) {
+	r := &WorkloadResources{}
+	if b, ok := resources["cpumem"]; ok {
+		cpumem := resourcetypes.RawParams{}
+		if err := json.Unmarshal(b, &cpumem); err != nil {
+			return nil, err
+		}
+		r.CPURequest = cpumem.Float64("cpu-request")
+		r.CPULimit = cpumem.Float64("cpu-limit")
+		r.MemoryRequest = cpumem.Int64("memory-request")
+		r.MemoryLimit = cpumem.Int64("memory-limit")
+		r.CPUBind = cpumem.Bool("cpu-bind")
+	}
+	if b, ok := resources["storage"]; ok {
+		storage := resourcetypes.RawParams{}
+		if err := json.Unmarshal(b, &storage); err != nil {
+			return nil, err
+		}
+		r.StorageRequest = storage.Int64("storage-request")
+		r.StorageLimit = storage.Int64("storage-limit")
+		r.VolumesRequest = storage.StringSlice("volumes-request")
+		r.VolumesLimit = storage.StringSlice("volumes-limit")
+	}
+	r.Normalize()
+	return r, nil
+}
+
+// Normalize fills requests and limits the same way eru-core does,
+// request follows limit if not given, and limit is never less than request
+func (r *WorkloadResources) Normalize() {
+	if r.CPURequest == 0 && r.CPULimit > 0 {
+		r.CPURequest = r.CPULimit
+	}
+	if r.CPULimit > 0 && r.CPULimit < r.CPURequest {
+		r.CPULimit = r.CPURequest
+	}
+	if r.MemoryRequest == 0 && r.MemoryLimit > 0 {
+		r.MemoryRequest = r.MemoryLimit
+	}
+	if r.MemoryLimit > 0 && r.MemoryLimit < r.MemoryRequest {
+		r.MemoryLimit = r.MemoryRequest
+	}
+	if r.StorageRequest == 0 && r.StorageLimit > 0 {
+		r.StorageRequest = r.StorageLimit
+	}
+	if r.StorageLimit > 0 && r.StorageLimit < r.StorageRequest {
+		r.StorageLimit = r.StorageRequest
+	}
+}
+
+// Add returns resources increased by delta, like eru-core does when reallocating,
+// volumes are kept as they are
+func (r *WorkloadResources) Add(delta *WorkloadResources) *WorkloadResources {
+	res := *r
+	res.CPURequest += delta.CPURequest
+	res.CPULimit += delta.CPULimit
+	res.MemoryRequest += delta.MemoryRequest
+	res.MemoryLimit += delta.MemoryLimit
+	res.StorageRequest += delta.StorageRequest
+	res.StorageLimit += delta.StorageLimit
+	res.Normalize()
+	return &res
+}
+
+// ToDeployResources converts back to the resources format used by DeployOptions
+func (r *WorkloadResources) ToDeployResources() map[string][]byte {
+	cpumem := resourcetypes.RawParams{
+		"cpu-request":    r.CPURequest,
+		"cpu-limit":      r.CPULimit,
+		"memory-request": r.MemoryRequest,
+		"memory-limit":   r.MemoryLimit,
+	}
+	if r.CPUBind {
+		cpumem["cpu-bind"] = true
+	}
+	storage := resourcetypes.RawParams{
+		"storage-request": r.StorageRequest,
+		"storage-limit":   r.StorageLimit,
+		"volumes-request": r.VolumesRequest,
+		"volumes-limit":   r.VolumesLimit,
+	}
+
+	cb, _ := json.Marshal(cpumem)
+	sb, _ := json.Marshal(storage)
+	return map[string][]byte{
+		"cpumem":  cb,
+		"storage": sb,
+	}
+}
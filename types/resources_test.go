@@ -0,0 +1,97 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name     string
+		r        WorkloadResources
+		expected WorkloadResources
+	}{
+		{"empty", WorkloadResources{}, WorkloadResources{}},
+		{"request follows limit", WorkloadResources{CPULimit: 2, MemoryLimit: 1024, StorageLimit: 2048},
+			WorkloadResources{CPURequest: 2, CPULimit: 2, MemoryRequest: 1024, MemoryLimit: 1024, StorageRequest: 2048, StorageLimit: 2048}},
+		{"limit not less than request", WorkloadResources{CPURequest: 2, CPULimit: 1, MemoryRequest: 2048, MemoryLimit: 1024, StorageRequest: 10, StorageLimit: 5},
+			WorkloadResources{CPURequest: 2, CPULimit: 2, MemoryRequest: 2048, MemoryLimit: 2048, StorageRequest: 10, StorageLimit: 10}},
+		{"request without limit", WorkloadResources{CPURequest: 1, MemoryRequest: 512},
+			WorkloadResources{CPURequest: 1, MemoryRequest: 512}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := c.r
+			r.Normalize()
+			if !reflect.DeepEqual(r, c.expected) {
+				t.Errorf("Normalize() = %+v, want %+v", r, c.expected)
+			}
+		})
+	}
+}
+
+func TestDeployResourcesRoundTrip(t *testing.T) {
+	r := &WorkloadResources{
+		CPURequest:     0.5,
+		CPULimit:       1,
+		CPUBind:        true,
+		MemoryRequest:  512 << 20,
+		MemoryLimit:    1 << 30,
+		StorageRequest: 1 << 30,
+		StorageLimit:   1 << 30,
+		VolumesRequest: []string{"/data:/data:rw:1G"},
+		VolumesLimit:   []string{"/data:/data:rw:1G"},
+	}
+	parsed, err := ParseDeployResources(r.ToDeployResources())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, r) {
+		t.Errorf("ParseDeployResources() = %+v, want %+v", parsed, r)
+	}
+}
+
+func TestParseWorkloadResources(t *testing.T) {
+	r, err := ParseWorkloadResources(`{"cpumem": {"cpu_request": 1, "cpu_limit": 2, "memory_request": 1024, "memory_limit": 2048, "cpu_map": {"0": 100}}, "storage": {"storage_request": 10, "storage_limit": 20}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &WorkloadResources{CPURequest: 1, CPULimit: 2, CPUBind: true, MemoryRequest: 1024, MemoryLimit: 2048, StorageRequest: 10, StorageLimit: 20}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("ParseWorkloadResources() = %+v, want %+v", r, expected)
+	}
+
+	if r, err := ParseWorkloadResources(""); err != nil || !reflect.DeepEqual(r, &WorkloadResources{}) {
+		t.Errorf("ParseWorkloadResources(\"\") = %+v, %v", r, err)
+	}
+	if _, err := ParseWorkloadResources("{"); err == nil {
+		t.Error("ParseWorkloadResources() with invalid json should fail")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	r := &WorkloadResources{CPURequest: 1, CPULimit: 2, MemoryRequest: 1024, MemoryLimit: 1024, VolumesRequest: []string{"/data:/data:rw:1G"}}
+	cases := []struct {
+		name     string
+		delta    *WorkloadResources
+		expected *WorkloadResources
+	}{
+		{"nothing", &WorkloadResources{}, r},
+		{"increase", &WorkloadResources{CPURequest: 0.5, MemoryLimit: 1024},
+			&WorkloadResources{CPURequest: 1.5, CPULimit: 2, MemoryRequest: 1024, MemoryLimit: 2048, VolumesRequest: []string{"/data:/data:rw:1G"}}},
+		{"request over limit raises limit", &WorkloadResources{CPURequest: 2, MemoryRequest: 1024},
+			&WorkloadResources{CPURequest: 3, CPULimit: 3, MemoryRequest: 2048, MemoryLimit: 2048, VolumesRequest: []string{"/data:/data:rw:1G"}}},
+		{"decrease", &WorkloadResources{CPULimit: -1},
+			&WorkloadResources{CPURequest: 1, CPULimit: 1, MemoryRequest: 1024, MemoryLimit: 1024, VolumesRequest: []string{"/data:/data:rw:1G"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := r.Add(c.delta); !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("Add() = %+v, want %+v", got, c.expected)
+			}
+		})
+	}
+	if r.CPURequest != 1 || r.MemoryLimit != 1024 {
+		t.Errorf("Add() changes the receiver, %+v", r)
+	}
+}
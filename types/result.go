@@ -0,0 +1,12 @@
+package types
+
+// EntrypointResult is the result of deploying an entrypoint
+type EntrypointResult struct {
+	Entrypoint string   `json:"entrypoint"`
+	Action     string   `json:"action"`
+	Succeeded  int      `json:"succeeded"`
+	Failed     int      `json:"failed"`
+	IDs        []string `json:"ids,omitempty"`
+	Skipped    bool     `json:"skipped,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/projecteru2/core/types"
+)
+
+// reserved labels stamped on workloads by deploy and replace
+const (
+	revisionLabelPrefix = "eru-cli."
+
+	// LabelRevision identifies the deploy or replace creating the workload
+	LabelRevision = revisionLabelPrefix + "revision"
+	// LabelSpecHash is the hash of specs
+	LabelSpecHash = revisionLabelPrefix + "spec-hash"
+	// LabelDeployer is who deployed, user@host
+	LabelDeployer = revisionLabelPrefix + "deployer"
+	// LabelDeployedAt is when deployed, in RFC3339 of UTC
+	LabelDeployedAt = revisionLabelPrefix + "deployed-at"
+	// LabelCLIVersion is version of cli deploying
+	LabelCLIVersion = revisionLabelPrefix + "version"
+	// LabelEntrypoint keeps entrypoint options not carried by workloads, in json
+	LabelEntrypoint = revisionLabelPrefix + "entrypoint"
+)
+
+// IsReservedLabel returns if the label is reserved by cli
+func IsReservedLabel(key string) bool {
+	return strings.HasPrefix(key, revisionLabelPrefix)
+}
+
+// EntrypointLabel is the value of LabelEntrypoint,
+// used to export specs back from workloads
+type EntrypointLabel struct {
+	Commands   []string          `json:"commands,omitempty"`
+	Dir        string            `json:"dir,omitempty"`
+	Restart    string            `json:"restart,omitempty"`
+	Sysctls    map[string]string `json:"sysctls,omitempty"`
+	Hook       *types.Hook       `json:"hook,omitempty"`
+	Log        *types.LogConfig  `json:"log,omitempty"`
+	DNS        []string          `json:"dns,omitempty"`
+	ExtraHosts []string          `json:"extra_hosts,omitempty"`
+	User       string            `json:"user,omitempty"`
+	// Files are names of files shipped when deploying, contents are not kept
+	Files []string `json:"files,omitempty"`
+}
+
+// Revision is a generation of workloads created by a deploy or replace
+type Revision struct {
+	Revision    string   `json:"revision"`
+	Image       string   `json:"image"`
+	SpecHash    string   `json:"spec_hash"`
+	Deployer    string   `json:"deployer"`
+	DeployedAt  string   `json:"deployed_at"`
+	CLIVersion  string   `json:"cli_version"`
+	Entrypoints []string `json:"entrypoints"`
+	Count       int      `json:"count"`
+}
@@ -1,9 +1,11 @@
 package types
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/projecteru2/core/types"
+	"gopkg.in/yaml.v2"
 )
 
 // Specs correspond to app.yaml in repository
@@ -15,12 +17,55 @@ type Specs struct {
 	Labels         map[string]string     `yaml:"labels,omitempty,flow"`
 	DNS            []string              `yaml:"dns,omitempty,flow"`
 	ExtraHosts     []string              `yaml:"extra_hosts,omitempty,flow"`
+
+	// EntrypointNames keeps the declared order of entrypoints
+	EntrypointNames []string `yaml:"-"`
+}
+
+// UnmarshalYAML decodes specs and records the declared order of entrypoints
+func (s *Specs) UnmarshalYAML(unmarshal func(any) error) error {
+	type plain Specs
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+
+	doc := yaml.MapSlice{}
+	if err := unmarshal(&doc); err != nil {
+		return err
+	}
+	for _, item := range doc {
+		if item.Key != "entrypoints" {
+			continue
+		}
+		entries, _ := item.Value.(yaml.MapSlice)
+		for _, entry := range entries {
+			s.EntrypointNames = append(s.EntrypointNames, fmt.Sprint(entry.Key))
+		}
+	}
+	return nil
 }
 
 // Entrypoint is a facade of old stype `cmd` and new stype `commands`
 type Entrypoint struct {
 	types.Entrypoint `yaml:",inline"`
-	Command          string `yaml:"cmd,omitempty"`
+	Command          string            `yaml:"cmd,omitempty"`
+	Deploy           *EntrypointDeploy `yaml:"deploy,omitempty"`
+}
+
+// EntrypointDeploy holds per entrypoint deploy options,
+// they are used unless the corresponding flags are given
+type EntrypointDeploy struct {
+	Count          int     `yaml:"count,omitempty"`
+	Network        string  `yaml:"network,omitempty"`
+	CPU            float64 `yaml:"cpu,omitempty"`
+	CPURequest     float64 `yaml:"cpu_request,omitempty"`
+	CPULimit       float64 `yaml:"cpu_limit,omitempty"`
+	Memory         string  `yaml:"memory,omitempty"`
+	MemoryRequest  string  `yaml:"memory_request,omitempty"`
+	MemoryLimit    string  `yaml:"memory_limit,omitempty"`
+	Storage        string  `yaml:"storage,omitempty"`
+	StorageRequest string  `yaml:"storage_request,omitempty"`
+	StorageLimit   string  `yaml:"storage_limit,omitempty"`
 }
 
 // GetCommands .
@@ -0,0 +1,10 @@
+package types
+
+// WorkloadsStatistics is the statistics of a group of workloads
+type WorkloadsStatistics struct {
+	// Group is empty if not grouped
+	Group string `json:"group,omitempty"`
+	Count int    `json:"count"`
+	// Resources is plugin -> param -> total, only params of requests and limits are summed
+	Resources map[string]map[string]float64 `json:"resources"`
+}